@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"videoCreater/jobs"
+)
+
+// Server is the HTTP API used to trigger and follow jobs
+//
+//	POST /jobs                   queue a job, the body is a jobs.Request
+//	GET  /jobs?limit=N&state=S   list the most recent jobs, newest first, optionally only those in state S
+//	GET  /jobs/{id}              status of a job with the progress of every stage
+//	POST /jobs/{id}/cancel       cancel a queued, retrying or running job
+//	POST /jobs/{id}/requeue      queue a dead or cancelled job again
+//	GET  /jobs/{id}/artifact?part=N  download a rendered video, part defaults to 1
+type Server struct {
+	manager *jobs.Manager
+}
+
+// NewServer creates an API serving the jobs of the manager
+func NewServer(manager *jobs.Manager) *Server {
+	return &Server{manager: manager}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return mux
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		limit := 20
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", value))
+				return
+			}
+		}
+		state := jobs.State(r.URL.Query().Get("state"))
+		writeJSON(w, http.StatusOK, s.manager.List(limit, state))
+
+	case http.MethodPost:
+		var req jobs.Request
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job: %v", err))
+			return
+		}
+		job, err := s.manager.Submit(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch action {
+	case "":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		job, ok := s.manager.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+
+	case "cancel":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		if _, ok := s.manager.Get(id); !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+			return
+		}
+		if err := s.manager.Cancel(id); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		job, _ := s.manager.Get(id)
+		writeJSON(w, http.StatusAccepted, job)
+
+	case "requeue":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		if _, ok := s.manager.Get(id); !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+			return
+		}
+		job, err := s.manager.Requeue(id)
+		if err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+
+	case "artifact":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.serveArtifact(w, r, id)
+
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+func (s *Server) serveArtifact(w http.ResponseWriter, r *http.Request, id string) {
+	job, ok := s.manager.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+		return
+	}
+	if !job.Finished() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", id, job.State))
+		return
+	}
+
+	part := 1
+	if value := r.URL.Query().Get("part"); value != "" {
+		var err error
+		if part, err = strconv.Atoi(value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid part %q", value))
+			return
+		}
+	}
+	if part < 1 || part > len(job.Artifacts) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s has %d artifacts", id, len(job.Artifacts)))
+		return
+	}
+
+	path := job.Artifacts[part-1]
+	if _, err := os.Stat(path); err != nil {
+		writeError(w, http.StatusGone, fmt.Errorf("artifact is no longer available, it is deleted after posting unless keepFiles is set"))
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+	http.ServeFile(w, r, path)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/jobs"
+	"videoCreater/pipeline"
+	"videoCreater/progress"
+	"videoCreater/workspace"
+)
+
+// video is what the stub renderer writes as the rendered video
+const video = "not really a video"
+
+func init() {
+	pipeline.RegisterSource("stub", stubSource{})
+	pipeline.RegisterSource("stub-blocking", stubSource{block: true})
+	pipeline.RegisterNarrator("stub", stubNarrator{})
+	pipeline.RegisterFootage("stub", stubFootage{})
+	pipeline.RegisterRenderer("stub", stubRenderer{})
+	pipeline.RegisterPublisher("stub", stubPublisher{})
+}
+
+// stubSource returns the same content every time, or with block set waits until the run is cancelled
+type stubSource struct {
+	block bool
+}
+
+func (stubSource) Check(in pipeline.Input) error { return nil }
+
+func (s stubSource) Fetch(ctx context.Context, run *pipeline.Run) (*pipeline.Content, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &pipeline.Content{Heading: "Heading", Text: "Some text.", Title: "Title"}, nil
+}
+
+type stubNarrator struct{}
+
+func (stubNarrator) Narrate(ctx context.Context, run *pipeline.Run, text string, settings config.Voice) (*pipeline.Narration, error) {
+	path := run.Workspace.Path(workspace.TextToSpeeched, "voice1.mp3")
+	return &pipeline.Narration{Files: []string{path}}, os.WriteFile(path, []byte(text), 0644)
+}
+
+type stubFootage struct{}
+
+func (stubFootage) Fetch(ctx context.Context, run *pipeline.Run, content *pipeline.Content, narration *pipeline.Narration) ([]pipeline.Footage, error) {
+	return nil, nil
+}
+
+type stubRenderer struct{}
+
+func (stubRenderer) Render(ctx context.Context, run *pipeline.Run, footage []pipeline.Footage, narration *pipeline.Narration, content *pipeline.Content) ([]string, error) {
+	path := run.Workspace.Path(workspace.Rendered, run.ID+".mp4")
+	return []string{path}, os.WriteFile(path, []byte(video), 0644)
+}
+
+// stubPublisher never posts, so the rendered video stays in the output dir
+type stubPublisher struct{}
+
+func (stubPublisher) Options(profile *config.Profile) (bool, bool)          { return false, false }
+func (stubPublisher) Describe(video *bundle.Video, profile *config.Profile) {}
+func (stubPublisher) Publish(ctx context.Context, path string, video *bundle.Video) (string, error) {
+	return "", nil
+}
+
+// newTestServer returns an API whose jobs run the stub pipelines, with every directory in a temporary directory
+func newTestServer(t *testing.T) *httptest.Server {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Profiles["default"].OutputDir = filepath.Join(dir, "output")
+	cfg.Workspace.Dir = filepath.Join(dir, "workspaces")
+	cfg.Workspace.CheckpointDir = filepath.Join(dir, "checkpoints")
+	cfg.History.Dir = filepath.Join(dir, "history")
+	cfg.ContentHistory.Dir = filepath.Join(dir, "content-history")
+	cfg.Pipelines = map[string]*config.Pipeline{
+		"stub":          {Source: "stub", Narrator: "stub", Footage: "stub", Renderer: "stub", Publisher: "stub"},
+		"stub-blocking": {Source: "stub-blocking", Narrator: "stub", Footage: "stub", Renderer: "stub", Publisher: "stub"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := jobs.NewManager(cfg, 1, 0, nil)
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(NewServer(manager).Handler())
+	t.Cleanup(func() {
+		server.Close()
+		for _, job := range manager.List(0, "") {
+			if !job.Finished() {
+				manager.Cancel(job.ID)
+			}
+		}
+		cancel()
+		manager.Shutdown()
+	})
+	return server
+}
+
+// do sends a request to the API and decodes the JSON response into v, returning the status code
+func do(t *testing.T, server *httptest.Server, method, path string, body interface{}, v interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitFor polls the job until it is in state, failing the test if that takes too long
+func waitFor(t *testing.T, server *httptest.Server, id string, state jobs.State) jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job jobs.Job
+		if status := do(t, server, http.MethodGet, "/jobs/"+id, nil, &job); status != http.StatusOK {
+			t.Fatalf("GET /jobs/%s: status %d", id, status)
+		}
+		if job.State == state {
+			return job
+		}
+		if job.Finished() || time.Now().After(deadline) {
+			t.Fatalf("job %s is %s (%s), want %s", id, job.State, job.Error, state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubmitJob(t *testing.T) {
+	server := newTestServer(t)
+
+	var job jobs.Job
+	if status := do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "stub"}, &job); status != http.StatusAccepted {
+		t.Fatalf("POST /jobs: status %d, want %d", status, http.StatusAccepted)
+	}
+	if job.ID == "" || job.State != jobs.Queued {
+		t.Fatalf("POST /jobs: got job %q in state %s, want a queued job", job.ID, job.State)
+	}
+
+	job = waitFor(t, server, job.ID, jobs.Succeeded)
+	want := map[progress.Stage]progress.Status{
+		progress.FetchContent: progress.Done,
+		progress.TTS:          progress.Done,
+		progress.Footage:      progress.Done,
+		progress.Render:       progress.Done,
+		progress.Upload:       progress.Done,
+	}
+	if len(job.Stages) != len(want) {
+		t.Fatalf("job has %d stages, want %d: %+v", len(job.Stages), len(want), job.Stages)
+	}
+	for _, stage := range job.Stages {
+		if stage.Status != want[stage.Stage] {
+			t.Errorf("stage %s is %s, want %s", stage.Stage, stage.Status, want[stage.Stage])
+		}
+	}
+	if len(job.Artifacts) != 1 {
+		t.Fatalf("job has %d artifacts, want 1", len(job.Artifacts))
+	}
+}
+
+func TestSubmitInvalidJob(t *testing.T) {
+	server := newTestServer(t)
+
+	var body map[string]string
+	if status := do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "unknown"}, &body); status != http.StatusBadRequest {
+		t.Fatalf("POST /jobs: status %d, want %d", status, http.StatusBadRequest)
+	}
+	if body["error"] == "" {
+		t.Error("POST /jobs: the response has no error")
+	}
+	if status := do(t, server, http.MethodGet, "/jobs/unknown", nil, &body); status != http.StatusNotFound {
+		t.Errorf("GET /jobs/unknown: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	server := newTestServer(t)
+
+	var first, second jobs.Job
+	do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "stub"}, &first)
+	waitFor(t, server, first.ID, jobs.Succeeded)
+	do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "stub-blocking"}, &second)
+	waitFor(t, server, second.ID, jobs.Running)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{second.ID, first.ID}},
+		{"?limit=1", []string{second.ID}},
+		{"?state=succeeded", []string{first.ID}},
+		{"?state=running", []string{second.ID}},
+		{"?state=dead", nil},
+	}
+	for _, test := range tests {
+		var list []jobs.Job
+		if status := do(t, server, http.MethodGet, "/jobs"+test.query, nil, &list); status != http.StatusOK {
+			t.Fatalf("GET /jobs%s: status %d", test.query, status)
+		}
+		var ids []string
+		for _, job := range list {
+			ids = append(ids, job.ID)
+		}
+		if len(ids) != len(test.want) || (len(ids) > 0 && ids[0] != test.want[0]) {
+			t.Errorf("GET /jobs%s: got %v, want %v", test.query, ids, test.want)
+		}
+	}
+
+	var body map[string]string
+	if status := do(t, server, http.MethodGet, "/jobs?limit=x", nil, &body); status != http.StatusBadRequest {
+		t.Errorf("GET /jobs?limit=x: status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	server := newTestServer(t)
+
+	var job jobs.Job
+	do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "stub-blocking"}, &job)
+	waitFor(t, server, job.ID, jobs.Running)
+
+	if status := do(t, server, http.MethodPost, "/jobs/"+job.ID+"/cancel", nil, &job); status != http.StatusAccepted {
+		t.Fatalf("POST /jobs/%s/cancel: status %d, want %d", job.ID, status, http.StatusAccepted)
+	}
+	job = waitFor(t, server, job.ID, jobs.Cancelled)
+	if job.Stages[0].Stage != progress.FetchContent || job.Stages[0].Status != progress.Failed {
+		t.Errorf("stage %s is %s, want %s %s", job.Stages[0].Stage, job.Stages[0].Status, progress.FetchContent, progress.Failed)
+	}
+
+	var body map[string]string
+	if status := do(t, server, http.MethodPost, "/jobs/"+job.ID+"/cancel", nil, &body); status != http.StatusConflict {
+		t.Errorf("cancelling a cancelled job: status %d, want %d", status, http.StatusConflict)
+	}
+	if status := do(t, server, http.MethodGet, "/jobs/"+job.ID+"/cancel", nil, &body); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET /jobs/%s/cancel: status %d, want %d", job.ID, status, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	server := newTestServer(t)
+
+	var job jobs.Job
+	do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "stub"}, &job)
+	waitFor(t, server, job.ID, jobs.Succeeded)
+
+	resp, err := server.Client().Get(server.URL + "/jobs/" + job.ID + "/artifact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(data) != video {
+		t.Fatalf("GET /jobs/%s/artifact: status %d and %q, want %d and %q", job.ID, resp.StatusCode, data, http.StatusOK, video)
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != `attachment; filename="`+job.ID+`.mp4"` {
+		t.Errorf("Content-Disposition is %q", disposition)
+	}
+
+	var body map[string]string
+	if status := do(t, server, http.MethodGet, "/jobs/"+job.ID+"/artifact?part=2", nil, &body); status != http.StatusNotFound {
+		t.Errorf("GET /jobs/%s/artifact?part=2: status %d, want %d", job.ID, status, http.StatusNotFound)
+	}
+
+	var running jobs.Job
+	do(t, server, http.MethodPost, "/jobs", jobs.Request{Type: "stub-blocking"}, &running)
+	waitFor(t, server, running.ID, jobs.Running)
+	if status := do(t, server, http.MethodGet, "/jobs/"+running.ID+"/artifact", nil, &body); status != http.StatusConflict {
+		t.Errorf("artifact of a running job: status %d, want %d", status, http.StatusConflict)
+	}
+}
@@ -0,0 +1,190 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"videoCreater/voice"
+)
+
+// MetadataFile is the name of the file describing the bundle
+const MetadataFile string = "metadata.json"
+
+// SourceFile is the name of the file holding the content the video was made from
+const SourceFile string = "source.json"
+
+// Bundle is a rendered video, or a video split in parts, together with everything needed to publish it later
+type Bundle struct {
+	Dir       string    `json:"-"`         // Directory the bundle is stored in, empty if it has not been written
+	Type      string    `json:"type"`      // Video type, the name of the pipeline it was made with
+	Profile   string    `json:"profile"`   // Profile the bundle was rendered with
+	CreatedAt time.Time `json:"createdAt"` // When the bundle was rendered
+	Videos    []*Video  `json:"videos"`
+}
+
+// Video is a single video file and how it should be published
+type Video struct {
+	File        string           `json:"file"`     // Path of the video, relative to the bundle directory once written
+	Captions    string           `json:"captions"` // Path of the SRT caption file, relative to the bundle directory
+	Platform    string           `json:"platform"` // Publisher of the video: youtube or tiktok
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Tags        []string         `json:"tags"`        // Only used by YouTube
+	CategoryID  string           `json:"categoryID"`  // Only used by YouTube
+	Privacy     string           `json:"privacy"`     // public, unlisted or private
+	MadeForKids bool             `json:"madeForKids"` // Only used by YouTube
+	PublishedID string           `json:"publishedID"` // ID of the uploaded video, empty until published
+	PublishedAt *time.Time       `json:"publishedAt"` // When the video was published, nil until published
+	WordTimings []voice.WordInfo `json:"-"`           // Used to write the caption file
+}
+
+// Write moves the videos into a new bundle directory under dir, and writes the metadata, captions and source content next to them
+func (b *Bundle) Write(dir string, source interface{}) error {
+	bundleDir, err := createBundleDir(dir, b.Type)
+	if err != nil {
+		return err
+	}
+
+	sourceData, err := json.MarshalIndent(source, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal source content: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(bundleDir, SourceFile), sourceData, 0644); err != nil {
+		return fmt.Errorf("failed to write source content: %v", err)
+	}
+
+	for i, video := range b.Videos {
+		name := fmt.Sprintf("part%d", i+1)
+		if len(b.Videos) == 1 {
+			name = "video"
+		}
+
+		file := name + filepath.Ext(video.File)
+		if err := MoveFile(video.File, filepath.Join(bundleDir, file)); err != nil {
+			return fmt.Errorf("failed to move %s into the bundle: %v", video.File, err)
+		}
+		video.File = file
+
+		video.Captions = name + ".srt"
+		if err := os.WriteFile(filepath.Join(bundleDir, video.Captions), []byte(toSRT(video.WordTimings)), 0644); err != nil {
+			return fmt.Errorf("failed to write captions: %v", err)
+		}
+	}
+
+	b.Dir = bundleDir
+	return b.Save()
+}
+
+// Load reads a bundle previously written by Write
+func Load(dir string) (*Bundle, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle metadata: %v", err)
+	}
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle metadata: %v", err)
+	}
+	if len(b.Videos) == 0 {
+		return nil, fmt.Errorf("bundle %s has no videos", dir)
+	}
+	b.Dir = dir
+	return &b, nil
+}
+
+// Path resolves a file of the bundle. Files of bundles that have not been written are used as is.
+func (b *Bundle) Path(file string) string {
+	if b.Dir == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(b.Dir, file)
+}
+
+// Save writes the metadata of a bundle that has been written
+func (b *Bundle) Save() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle metadata: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(b.Dir, MetadataFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle metadata: %v", err)
+	}
+	return nil
+}
+
+// createBundleDir creates a new directory named after the video type and the current time
+func createBundleDir(dir, videoType string) (string, error) {
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", videoType, time.Now().Format("20060102-150405")))
+	path := base
+	for i := 2; ; i++ {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.Mkdir(path, 0755)
+		}
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create bundle directory: %v", err)
+		}
+		path = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// MoveFile renames a file, falling back to copying it when it is on another filesystem
+func MoveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	if err := copyFile(from, to); err != nil {
+		os.Remove(to) // A partial copy is no video
+		return fmt.Errorf("failed to move %s to %s: %v", from, to, err)
+	}
+	if err := os.Remove(from); err != nil {
+		return fmt.Errorf("moved %s to %s, but failed to remove it: %v", from, to, err)
+	}
+	return nil
+}
+
+// copyFile streams a file to a new file and syncs it to disk, so the original can be removed
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(to, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// toSRT turns word timings into an SRT caption file with one word per cue
+func toSRT(wordTimings []voice.WordInfo) string {
+	var sb strings.Builder
+	for i, word := range wordTimings {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, srtTimestamp(word.StartTime), srtTimestamp(word.EndTime), word.Word)
+	}
+	return sb.String()
+}
+
+// srtTimestamp formats seconds as HH:MM:SS,mmm
+func srtTimestamp(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"videoCreater/bundle"
+	"videoCreater/doctor"
+	"videoCreater/jobs"
+	"videoCreater/pipeline"
+	"videoCreater/upload"
+)
+
+// commands maps the first calling argument to the function running that command
+var commands = map[string]func(args []string) error{
+	"quote":   runQuote,
+	"reddit":  runReddit,
+	"render":  runRender,
+	"upload":  runUpload,
+	"publish": runPublish,
+	"serve":   runServe,
+	"daemon":  runServe,
+	"doctor":  runDoctor,
+	"queue":   runQueue,
+	"history": runHistory,
+	"resume":  runResume,
+}
+
+// runFlags are the flags shared by every command that makes a video
+type runFlags struct {
+	config    string
+	profile   string
+	voice     string
+	outputDir string
+	noUpload  bool
+	keepFiles bool
+	dryRun    bool
+
+	keepWorkspace bool
+}
+
+// register adds the shared flags to the flag set. Publishing flags are left out for commands that never publish.
+func (f *runFlags) register(fs *flag.FlagSet, publishing bool) {
+	fs.StringVar(&f.config, "config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	fs.StringVar(&f.profile, "profile", "", "profile to use (default $VIDEOCREATER_PROFILE or the config's defaultProfile)")
+	fs.StringVar(&f.voice, "voice", "", "voice id to narrate with, overrides voice.id")
+	fs.StringVar(&f.outputDir, "output-dir", "", "directory the finished videos are written to, overrides outputDir")
+	fs.BoolVar(&f.keepWorkspace, "keep-workspace", false, "keep the intermediate files of the run for debugging, overrides workspace.keep")
+	if publishing {
+		fs.BoolVar(&f.noUpload, "no-upload", false, "do not publish the video")
+		fs.BoolVar(&f.keepFiles, "keep-files", false, "keep the video after it has been published")
+		fs.BoolVar(&f.dryRun, "dry-run", false, "write the video to a bundle in the output dir instead of publishing it, see the publish command")
+	}
+}
+
+// run makes the video described by req with the flags applied. Ctrl-C cancels the run.
+func (f *runFlags) run(req jobs.Request) error {
+	cfg, err := initConfig(f.config)
+	if err != nil {
+		return err
+	}
+
+	req.Profile = f.profile
+	if req.Profile == "" {
+		req.Profile = os.Getenv("VIDEOCREATER_PROFILE")
+	}
+	req.Voice = f.voice
+	req.OutputDir = f.outputDir
+	req.KeepFiles = f.keepFiles
+	req.DryRun = req.DryRun || f.dryRun
+	req.KeepWorkspace = f.keepWorkspace
+	if f.noUpload {
+		noUpload := false
+		req.Youtube = &noUpload
+		req.TikTok = &noUpload
+		req.KeepFiles = true
+	}
+
+	profile, err := req.Resolve(cfg)
+	if err != nil {
+		return err
+	}
+	ensureDirExists(profile.OutputDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	id := jobs.NewID()
+	_, err = jobs.Execute(ctx, cfg, id, req, nil)
+	if err != nil {
+		if _, cpErr := pipeline.LoadCheckpoint(filepath.Join(cfg.CheckpointDir(), id)); cpErr == nil {
+			return fmt.Errorf("%v\nContinue the run with 'videocreater resume %s'", err, id)
+		}
+	}
+	return err
+}
+
+// newFlagSet creates a flag set that prints the given usage line and description on --help
+func newFlagSet(name, arguments, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: videocreater %s [flags] %s\n\n%s\n\nFlags:\n", name, arguments, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags that can come both before and after the positional arguments and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// exitOnHelp turns the help error from the flag package into a clean exit
+func exitOnHelp(err error) error {
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	return err
+}
+
+func runQuote(args []string) error {
+	fs := newFlagSet("quote", "", "Creates a random quote video and publishes it to the targets enabled in the profile.")
+	var flags runFlags
+	flags.register(fs, true)
+	theme := fs.String("theme", "", "theme of the quote, overrides the profile's themes")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("quote takes no arguments, got %s", strings.Join(positional, " "))
+	}
+
+	return flags.run(jobs.Request{Type: "quote", Theme: *theme})
+}
+
+func runReddit(args []string) error {
+	fs := newFlagSet("reddit", "<subreddit>", "Creates a video of the newest post in r/<subreddit> and publishes it to the targets enabled in the profile.")
+	var flags runFlags
+	flags.register(fs, true)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	// Ensure that subreddit argument is also provided
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("reddit requires the subreddit name")
+	}
+
+	return flags.run(jobs.Request{Type: "reddit", Subreddit: positional[0]})
+}
+
+func runRender(args []string) error {
+	fs := newFlagSet("render", "<type> [subreddit]", "Creates a video of a type (quote, reddit or a pipeline from the config) and writes it to a bundle in the output dir,\ntogether with its metadata, captions and source content. The bundle can be published later with the publish command.")
+	var flags runFlags
+	flags.register(fs, false)
+	theme := fs.String("theme", "", "theme of a quote video, overrides the profile's themes")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) == 0 {
+		fs.Usage()
+		return fmt.Errorf("render requires the video type")
+	}
+
+	req := jobs.Request{Type: positional[0], Theme: *theme, DryRun: true}
+	switch {
+	case len(positional) == 2:
+		req.Subreddit = positional[1]
+	case len(positional) > 2:
+		fs.Usage()
+		return fmt.Errorf("too many arguments for render %s", req.Type)
+	}
+	return flags.run(req)
+}
+
+func runUpload(args []string) error {
+	fs := newFlagSet("upload", "<video>", "Publishes an already rendered video.")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	profileName := fs.String("profile", "", "profile to use (default $VIDEOCREATER_PROFILE or the config's defaultProfile)")
+	platform := fs.String("platform", "youtube", "where to publish the video: youtube or tiktok")
+	title := fs.String("title", "", "title of the video")
+	description := fs.String("description", "", "description of the video")
+	categoryID := fs.String("category", "22", "YouTube category id")
+	tags := fs.String("tags", "", "comma separated YouTube tags (default the profile's themes)")
+	keepFiles := fs.Bool("keep-files", false, "keep the video after it has been published")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("upload requires the path to the video")
+	}
+	if *title == "" {
+		return fmt.Errorf("upload requires --title")
+	}
+	videoPath := positional[0]
+
+	cfg, err := initConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	profile, err := selectProfile(cfg, *profileName)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	deleteAfterPost := false
+	switch *platform {
+	case "youtube":
+		postTags := profile.Themes
+		if *tags != "" {
+			postTags = strings.Split(*tags, ",")
+		}
+		if _, err := upload.UploadVideoYoutube(ctx, videoPath, *description, *title, *categoryID, postTags, profile.Youtube.Privacy, profile.Youtube.MadeForKids); err != nil {
+			return fmt.Errorf("failed to upload video: %v", err)
+		}
+		deleteAfterPost = profile.Youtube.DeleteAfterPost
+
+	case "tiktok":
+		if err := upload.UploadVideoTikTok(ctx, videoPath, *title, *description); err != nil {
+			return fmt.Errorf("failed to upload video: %v", err)
+		}
+		deleteAfterPost = profile.TikTok.DeleteAfterPost
+
+	default:
+		return fmt.Errorf("unknown platform %q. Use 'youtube' or 'tiktok'", *platform)
+	}
+
+	if deleteAfterPost && !*keepFiles {
+		if err := os.Remove(videoPath); err != nil {
+			return fmt.Errorf("error deleting video file: %v", err)
+		}
+	}
+	return nil
+}
+
+func runPublish(args []string) error {
+	fs := newFlagSet("publish", "<bundle>", "Publishes a bundle written by render or --dry-run. Videos that were already published are skipped.")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	profileName := fs.String("profile", "", "profile to use (default the profile the bundle was rendered with)")
+	keepFiles := fs.Bool("keep-files", false, "keep the videos after they have been published")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("publish requires the path to the bundle")
+	}
+
+	b, err := bundle.Load(positional[0])
+	if err != nil {
+		return err
+	}
+	if *profileName == "" {
+		*profileName = b.Profile
+	}
+	cfg, err := initConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	base, err := selectProfile(cfg, *profileName)
+	if err != nil {
+		return err
+	}
+	profile := base.Clone()
+	if *keepFiles {
+		profile.Youtube.DeleteAfterPost = false
+		profile.TikTok.DeleteAfterPost = false
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := pipeline.Publish(ctx, b, profile, true); err != nil {
+		return err
+	}
+	log.Printf("Published bundle %s", b.Dir)
+	return nil
+}
+
+func runDoctor(args []string) error {
+	fs := newFlagSet("doctor", "", "Checks the config, env variables, ffmpeg, fonts, working dirs, the YouTube token and disk space.\nPrints a report and exits with a non-zero code if any check failed.")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	profileName := fs.String("profile", "", "profile to check (default $VIDEOCREATER_PROFILE or the config's defaultProfile)")
+	videoType := fs.String("type", "all", "video type that will be run: quote, reddit or all")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("doctor takes no arguments")
+	}
+
+	var features doctor.Features
+	switch *videoType {
+	case "quote":
+		features.Quote = true
+	case "reddit":
+		features.Reddit = true
+	case "all":
+		features = doctor.Features{Quote: true, Reddit: true}
+	default:
+		return fmt.Errorf("unknown video type %q. Use 'quote', 'reddit' or 'all'", *videoType)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		printResults([]doctor.Result{{Name: "config", Detail: err.Error()}})
+		return fmt.Errorf("doctor found problems")
+	}
+	profile, err := selectProfile(cfg, *profileName)
+	if err != nil {
+		printResults([]doctor.Result{{Name: "config", Detail: err.Error()}})
+		return fmt.Errorf("doctor found problems")
+	}
+
+	results := []doctor.Result{{Name: "config", OK: true, Detail: fmt.Sprintf("profile %q of %s", profile.Name, strings.Join(cfg.ProfileNames(), ", "))}}
+	results = append(results, doctor.Run(profile, cfg.WorkspaceDir(), cfg.ContentHistoryDir(), features)...)
+	printResults(results)
+
+	if doctor.Failed(results) {
+		return fmt.Errorf("doctor found problems")
+	}
+	return nil
+}
+
+// printResults prints one line per check
+func printResults(results []doctor.Result) {
+	for _, result := range results {
+		status := "PASS"
+		if !result.OK {
+			status = "FAIL"
+		}
+		fmt.Printf("[%s] %-28s %s\n", status, result.Name, result.Detail)
+	}
+}
@@ -0,0 +1,859 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"videoCreater/global"
+	"videoCreater/progress"
+	"videoCreater/safety"
+	"videoCreater/scheduler"
+)
+
+// DefaultPath is the config file loaded when VIDEOCREATER_CONFIG is not set
+const DefaultPath string = "config.json"
+
+// Config is the root of the config file
+type Config struct {
+	DefaultProfile string               `json:"defaultProfile"`
+	Profiles       map[string]*Profile  `json:"profiles"`
+	Daemon         Daemon               `json:"daemon"`
+	Queue          Queue                `json:"queue"`
+	Pipelines      map[string]*Pipeline `json:"pipelines"` // Video types on top of the built in quote and reddit
+	Workspace      Workspace            `json:"workspace"`
+	History        History              `json:"history"`
+	ContentHistory ContentHistory       `json:"contentHistory"`
+}
+
+// ContentHistory holds which quotes, posts and footage every channel used, so they are not used again
+type ContentHistory struct {
+	Dir    string `json:"dir"`    // Directory of the content history, defaults to "content-history"
+	Window string `json:"window"` // How long something used stays off limits, like 720h. Defaults to 2160h (90 days), 0 is forever
+}
+
+// History holds where the manifest of every run is kept
+type History struct {
+	Dir string `json:"dir"` // Directory of the history log and the run manifests, defaults to "run-history"
+}
+
+// Workspace holds where runs keep their intermediate files
+type Workspace struct {
+	Dir  string `json:"dir"`  // Parent directory of the workspace of every run, defaults to "workspaces"
+	Keep bool   `json:"keep"` // Keep the workspace after the run for debugging instead of removing it
+
+	CheckpointDir string `json:"checkpointDir"` // Where the outputs of finished stages are kept until the run succeeds, defaults to "checkpoints"
+}
+
+// Pipeline is a video type, composed of one implementation of every stage. See the pipeline package for the names.
+type Pipeline struct {
+	Source    string `json:"source"`    // Where the content comes from: favqs or reddit
+	Narrator  string `json:"narrator"`  // Text to speech: tts
+	Footage   string `json:"footage"`   // Background video: pexels or youtube-gameplay
+	Renderer  string `json:"renderer"`  // How the video is edited: youtube or tiktok
+	Publisher string `json:"publisher"` // Where the video is published: youtube or tiktok
+}
+
+// DefaultPipelines are the video types the bot shipped with. The config can override them.
+var DefaultPipelines = map[string]*Pipeline{
+	"quote":  {Source: "favqs", Narrator: "tts", Footage: "pexels", Renderer: "youtube", Publisher: "youtube"},
+	"reddit": {Source: "reddit", Narrator: "tts", Footage: "youtube-gameplay", Renderer: "tiktok", Publisher: "tiktok"},
+}
+
+// Queue holds where the job queue is stored and how failed jobs are retried
+type Queue struct {
+	Dir    string                  `json:"dir"`    // Directory of the job journal, defaults to "queue"
+	Retry  RetryPolicy             `json:"retry"`  // Used for stages without their own policy
+	Stages map[string]*RetryPolicy `json:"stages"` // Policy per stage: fetch-content, tts, footage, render or upload
+}
+
+// RetryPolicy decides how often and how long after a failure a job is tried again. The delay starts at Backoff
+// and is multiplied by Multiplier after every attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int     `json:"maxAttempts"` // Attempts before the job is moved to the dead letters, defaults to 3
+	Backoff     string  `json:"backoff"`     // Delay before the second attempt, defaults to "1m"
+	MaxBackoff  string  `json:"maxBackoff"`  // Longest delay, defaults to "1h"
+	Multiplier  float64 `json:"multiplier"`  // Defaults to 2
+}
+
+// Daemon holds the jobs run by the serve command
+type Daemon struct {
+	Concurrency int            `json:"concurrency"` // How many jobs can run at the same time, defaults to 1
+	API         string         `json:"api"`         // Address the HTTP API listens on, like 127.0.0.1:8090. Empty turns the API off.
+	KeepJobs    int            `json:"keepJobs"`    // How many finished jobs the API remembers, 0 remembers all
+	Jobs        []*ScheduleJob `json:"jobs"`
+}
+
+// ScheduleJob is a video made on a schedule. Exactly one of Cron, Every and PerDay has to be set.
+type ScheduleJob struct {
+	Name      string `json:"name"`
+	Profile   string `json:"profile"`   // Defaults to the default profile
+	Type      string `json:"type"`      // Name of the pipeline, like quote or reddit
+	Subreddit string `json:"subreddit"` // Required for reddit videos
+	Theme     string `json:"theme"`     // Optional theme of quote videos
+	DryRun    bool   `json:"dryRun"`    // Write a bundle instead of publishing
+	Cron      string `json:"cron"`      // Five field cron expression, like "0 9 * * *"
+	Every     string `json:"every"`     // Interval, like "4h"
+	PerDay    int    `json:"perDay"`    // Times a day, spread out over the day
+	Jitter    string `json:"jitter"`    // How far a perDay run can move from its slot, like "30m"
+}
+
+// Profile bundles everything that belongs to a single channel
+type Profile struct {
+	Name            string   `json:"-"` // Filled in from the key in Config.Profiles
+	Voice           Voice    `json:"voice"`
+	Themes          []string `json:"themes"`
+	Font            string   `json:"font"`
+	BorderThickness int      `json:"borderThickness"`
+	OutputDir       string   `json:"outputDir"` // Where the finished videos are written
+	Youtube         Youtube  `json:"youtube"`
+	TikTok          TikTok   `json:"tiktok"`
+	Reddit          Reddit   `json:"reddit"`  // Which posts are made into Reddit videos
+	Safety          Safety   `json:"safety"`  // Which Reddit posts are not fit for the channel
+	Censor          Censor   `json:"censor"`  // Swear words kept out of the narration and captions
+	Speech          Speech   `json:"speech"`  // How the narrator says the text
+	Persona         Persona  `json:"persona"` // Voices of Reddit videos picked after who wrote the post
+}
+
+// Persona picks the voice a Reddit post is narrated with after the age and gender its writer gives, like I (29F)
+type Persona struct {
+	Pools    []VoicePool `json:"pools"`    // The first pool the writer fits in is used
+	Fallback []string    `json:"fallback"` // Voices for writers that fit in no pool or do not say, taking turns video after video. voice.id if empty.
+}
+
+// VoicePool is the voices for writers of a gender and age band
+type VoicePool struct {
+	Gender string   `json:"gender"` // m, f or nb, empty for any
+	MinAge int      `json:"minAge"`
+	MaxAge int      `json:"maxAge"` // 0 for no limit
+	Voices []string `json:"voices"`
+}
+
+// Fits returns whether a writer of the gender and age fits in the pool
+func (v *VoicePool) Fits(gender string, age int) bool {
+	return (v.Gender == "" || v.Gender == gender) && age >= v.MinAge && (v.MaxAge == 0 || age <= v.MaxAge)
+}
+
+// Pool returns the voices for a writer of the gender and age, empty if they do not say, and a description of why
+// they fit, like pools[0]. Writers that fit in no pool get the fallback voices. It returns no voices if the profile
+// voice is used.
+func (p *Persona) Pool(gender string, age int) (voices []string, reason string) {
+	if gender != "" {
+		for i, pool := range p.Pools {
+			if pool.Fits(gender, age) {
+				return pool.Voices, fmt.Sprintf("pools[%d]", i)
+			}
+		}
+	}
+	if len(p.Fallback) > 0 {
+		return p.Fallback, "fallback"
+	}
+	return nil, ""
+}
+
+// Speech holds how the text of a video is said. The captions show the text as it is written.
+type Speech struct {
+	Expansions map[string]string `json:"expansions"` // What acronyms are said as, added to the built in ones. Empty to say an acronym as it is written.
+}
+
+// Safety keeps NSFW posts, spoilers, blocked words and personal information out of the videos of a channel
+type Safety struct {
+	AllowNSFW     bool          `json:"allowNsfw"`     // Use posts marked over 18
+	AllowSpoilers bool          `json:"allowSpoilers"` // Use posts marked as spoilers
+	Blocklist     []safety.Rule `json:"blocklist"`     // Words that reject a post or comment, or are masked in it
+	KeepPII       bool          `json:"keepPii"`       // Narrate and show links, email addresses, usernames, phone numbers and names
+}
+
+// Censor modes
+const (
+	CensorReplace = "replace" // The narrator says Replacement instead of a swear word
+	CensorBleep   = "bleep"   // A tone is played over a swear word
+)
+
+// Censor masks swear words in the captions and headings of a video, like f***, and keeps them out of the narration
+type Censor struct {
+	Mode        string   `json:"mode"`        // replace or bleep, empty to not censor
+	Words       []string `json:"words"`       // A * matches any letters, like f*ck or shit*
+	Replacement string   `json:"replacement"` // Said instead of a swear word in the replace mode, beep if empty
+}
+
+// Spoken returns what the narrator says instead of a swear word in the replace mode
+func (c *Censor) Spoken() string {
+	if c.Replacement == "" {
+		return "beep"
+	}
+	return c.Replacement
+}
+
+// Text to speech providers
+const (
+	UnrealSpeech = "unrealspeech"
+	GoogleTTS    = "google"
+	Piper        = "piper"  // Offline, with a piper .onnx voice model
+	Espeak       = "espeak" // Offline, with espeak-ng
+)
+
+// Voice holds the settings sent to the text to speech API
+type Voice struct {
+	Provider string  `json:"provider,omitempty"` // unrealspeech (the default), google, piper or espeak
+	ID       string  `json:"id"`                 // Scarlett, Liv, Amy, Dan or Will for UnrealSpeech, a voice name like en-US-Neural2-F for Google, the path to an .onnx model for piper, a voice like en-us for espeak
+	Speed    float64 `json:"speed"`              // -1 to 1
+	Pitch    float64 `json:"pitch"`              // 0.5 to 1.5
+	Bitrate  string  `json:"bitrate"`            // 320k, 256k, 192k, ... Not used by Google, optional for piper and espeak.
+	Lexicon  string  `json:"lexicon,omitempty"`  // JSON file of words and how the narrator should say them, like {"Nguyen": "win"}
+	Fallback *Voice  `json:"fallback,omitempty"` // Narrates instead when the provider fails, like when its quota is used up
+}
+
+// ProviderName returns the text to speech provider of the voice
+func (v *Voice) ProviderName() string {
+	if v.Provider == "" {
+		return UnrealSpeech
+	}
+	return v.Provider
+}
+
+// Youtube holds the channel name and publish settings for YouTube
+type Youtube struct {
+	ChannelName     string `json:"channelName"`
+	Post            bool   `json:"post"`
+	DeleteAfterPost bool   `json:"deleteAfterPost"`
+	MadeForKids     bool   `json:"madeForKids"`
+	Privacy         string `json:"privacy"` // public, unlisted or private
+}
+
+// TikTok holds the channel name and publish settings for TikTok
+type TikTok struct {
+	ChannelName     string `json:"channelName"`
+	Post            bool   `json:"post"`
+	DeleteAfterPost bool   `json:"deleteAfterPost"`
+}
+
+// Reddit holds which listing of a subreddit Reddit videos are made from and which posts are good enough.
+// Stickied, removed and deleted posts and posts without text are always skipped.
+type Reddit struct {
+	Listing        string  `json:"listing"`        // new, hot, rising or top, defaults to new
+	Time           string  `json:"time"`           // Window of the top listing: hour, day, week, month, year or all. Defaults to day
+	MinScore       int     `json:"minScore"`       // Fewest upvotes minus downvotes
+	MinUpvoteRatio float64 `json:"minUpvoteRatio"` // 0 to 1
+	MinComments    int     `json:"minComments"`
+	MinDuration    string  `json:"minDuration"` // Shortest estimated narration of the title and text, like 30s
+	MaxDuration    string  `json:"maxDuration"` // Longest estimated narration of the title and text, like 5m
+
+	Comments         int `json:"comments"`         // How many top comments are narrated after the post, 0 narrates none
+	MinCommentLength int `json:"minCommentLength"` // Fewest characters of a narrated comment
+	MaxCommentLength int `json:"maxCommentLength"` // Most characters of a narrated comment, 0 for no limit
+
+	Verdict bool `json:"verdict"` // Tally the YTA, NTA, ESH, NAH and INFO judgements in the comments and end the video with the verdict
+
+	// Title and description of the published video. {subreddit}, {date}, {postTitle}, {verdict}, {verdictName} and
+	// {verdictPercent} are replaced, the verdict placeholders are empty without a verdict.
+	Title       string `json:"title"`       // Defaults to "Reddit: {subreddit} - {date}"
+	Description string `json:"description"` // Defaults to "{subreddit}? Leave a comment about what you think!"
+}
+
+var voiceIDs = []string{"Scarlett", "Liv", "Amy", "Dan", "Will"}
+var privacies = []string{"public", "unlisted", "private"}
+var bitrates = []string{"320k", "256k", "192k", "128k", "64k", "32k", "16k"}
+var listings = []string{"new", "hot", "rising", "top"}
+var topTimes = []string{"hour", "day", "week", "month", "year", "all"}
+var censorModes = []string{CensorReplace, CensorBleep}
+var genders = []string{"m", "f", "nb"}
+var providers = []string{UnrealSpeech, GoogleTTS, Piper, Espeak}
+
+// Default returns the settings the bot shipped with before the config file existed
+func Default() *Config {
+	return &Config{
+		DefaultProfile: "default",
+		Profiles: map[string]*Profile{
+			"default": {
+				Name: "default",
+				Voice: Voice{
+					ID:      "Liv",
+					Speed:   0.1,
+					Pitch:   1,
+					Bitrate: "192k",
+				},
+				Themes:          []string{"love", "friendship", "happiness", "life", "courage", "trust"},
+				Font:            "fonts/PermanentMarker-Regular.ttf",
+				BorderThickness: 10,
+				OutputDir:       "edited-videos",
+				Youtube: Youtube{
+					ChannelName:     "QuotePixel",
+					Post:            true,
+					DeleteAfterPost: true,
+					Privacy:         "public",
+				},
+				TikTok: TikTok{
+					ChannelName: "TheRedditPixel",
+				},
+			},
+		},
+	}
+}
+
+// Load reads and validates the config file at path. Unknown keys are rejected.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	for name, profile := range cfg.Profiles {
+		if profile == nil {
+			return nil, fmt.Errorf("config file %s: profile %q is empty", path, name)
+		}
+		profile.Name = name
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that every profile only holds values the rest of the bot can use
+func (c *Config) Validate() error {
+	if len(c.Profiles) == 0 {
+		return fmt.Errorf("no profiles defined")
+	}
+	if _, ok := c.Profiles[c.DefaultProfile]; !ok {
+		return fmt.Errorf("defaultProfile %q does not match any profile (have: %s)", c.DefaultProfile, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	for _, name := range c.ProfileNames() {
+		if err := c.Profiles[name].Validate(); err != nil {
+			return fmt.Errorf("profile %q: %v", name, err)
+		}
+	}
+
+	if c.Daemon.Concurrency < 0 {
+		return fmt.Errorf("daemon.concurrency %d must not be negative", c.Daemon.Concurrency)
+	}
+	if err := c.Queue.Retry.validate(); err != nil {
+		return fmt.Errorf("queue.retry: %v", err)
+	}
+	for stage, policy := range c.Queue.Stages {
+		if !isStage(stage) {
+			return fmt.Errorf("queue.stages: unknown stage %q", stage)
+		}
+		if policy == nil {
+			return fmt.Errorf("queue.stages.%s is empty", stage)
+		}
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("queue.stages.%s: %v", stage, err)
+		}
+	}
+
+	for name, pipeline := range c.Pipelines {
+		if pipeline == nil {
+			return fmt.Errorf("pipelines.%s is empty", name)
+		}
+		stages := map[string]string{"source": pipeline.Source, "narrator": pipeline.Narrator, "footage": pipeline.Footage, "renderer": pipeline.Renderer, "publisher": pipeline.Publisher}
+		for stage, value := range stages {
+			if value == "" {
+				return fmt.Errorf("pipelines.%s: %s must be set", name, stage)
+			}
+		}
+	}
+
+	if c.ContentHistory.Window != "" {
+		d, err := time.ParseDuration(c.ContentHistory.Window)
+		if err != nil {
+			return fmt.Errorf("contentHistory.window: %v", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("contentHistory.window %s must not be negative", c.ContentHistory.Window)
+		}
+	}
+
+	if c.Daemon.KeepJobs < 0 {
+		return fmt.Errorf("daemon.keepJobs %d must not be negative", c.Daemon.KeepJobs)
+	}
+	names := make(map[string]bool)
+	for i, job := range c.Daemon.Jobs {
+		if job == nil || job.Name == "" {
+			return fmt.Errorf("daemon.jobs[%d]: name must be set", i)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("daemon.jobs[%d]: name %q is used twice", i, job.Name)
+		}
+		names[job.Name] = true
+		if err := c.validateJob(job); err != nil {
+			return fmt.Errorf("daemon job %q: %v", job.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateJob(job *ScheduleJob) error {
+	if _, err := c.Profile(job.Profile); err != nil {
+		return err
+	}
+	if _, err := c.Pipeline(job.Type); err != nil {
+		return err
+	}
+	_, err := job.Schedule()
+	return err
+}
+
+// QueueDir returns the directory of the job journal
+func (c *Config) QueueDir() string {
+	if c.Queue.Dir == "" {
+		return "queue"
+	}
+	return c.Queue.Dir
+}
+
+// WorkspaceDir returns the parent directory of the run workspaces
+func (c *Config) WorkspaceDir() string {
+	if c.Workspace.Dir == "" {
+		return "workspaces"
+	}
+	return c.Workspace.Dir
+}
+
+// CheckpointDir returns the parent directory of the run checkpoints
+func (c *Config) CheckpointDir() string {
+	if c.Workspace.CheckpointDir == "" {
+		return "checkpoints"
+	}
+	return c.Workspace.CheckpointDir
+}
+
+// HistoryDir returns the directory of the run history
+func (c *Config) HistoryDir() string {
+	if c.History.Dir == "" {
+		return "run-history"
+	}
+	return c.History.Dir
+}
+
+// ContentHistoryDir returns the directory of the content history
+func (c *Config) ContentHistoryDir() string {
+	if c.ContentHistory.Dir == "" {
+		return "content-history"
+	}
+	return c.ContentHistory.Dir
+}
+
+// ContentWindow returns how long used content is skipped, 0 for forever
+func (c *Config) ContentWindow() time.Duration {
+	return parseDuration(c.ContentHistory.Window, 90*24*time.Hour)
+}
+
+// RetryDelay returns how long to wait before trying a job again after its attempt-th attempt failed in stage.
+// It returns false if the job has used up its attempts. An empty stage uses the default policy.
+func (q *Queue) RetryDelay(stage string, attempt int) (time.Duration, bool) {
+	policy := q.Retry
+	if p, ok := q.Stages[stage]; ok {
+		policy = *p
+	}
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 3
+	}
+	if attempt >= maxAttempts {
+		return 0, false
+	}
+
+	backoff := parseDuration(policy.Backoff, time.Minute)
+	maxBackoff := parseDuration(policy.MaxBackoff, time.Hour)
+	multiplier := policy.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	delay := float64(backoff)
+	for i := 1; i < attempt && delay < float64(maxBackoff); i++ {
+		delay *= multiplier
+	}
+	if delay > float64(maxBackoff) {
+		delay = float64(maxBackoff)
+	}
+	return time.Duration(delay), true
+}
+
+func (p *RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("maxAttempts %d must not be negative", p.MaxAttempts)
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("multiplier %v must be at least 1", p.Multiplier)
+	}
+	for name, value := range map[string]string{"backoff": p.Backoff, "maxBackoff": p.MaxBackoff} {
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s %s must not be negative", name, value)
+		}
+	}
+	return nil
+}
+
+// parseDuration parses an already validated duration, returning fallback if it is empty
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if value == "" || err != nil {
+		return fallback
+	}
+	return d
+}
+
+func isStage(name string) bool {
+	for _, stage := range progress.Stages {
+		if string(stage) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule returns when the job runs
+func (j *ScheduleJob) Schedule() (scheduler.Schedule, error) {
+	set := 0
+	for _, isSet := range []bool{j.Cron != "", j.Every != "", j.PerDay != 0} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one of cron, every and perDay must be set")
+	}
+	if j.Jitter != "" && j.PerDay == 0 {
+		return nil, fmt.Errorf("jitter is only used with perDay")
+	}
+
+	switch {
+	case j.Cron != "":
+		return scheduler.ParseCron(j.Cron)
+	case j.Every != "":
+		interval, err := time.ParseDuration(j.Every)
+		if err != nil {
+			return nil, fmt.Errorf("every: %v", err)
+		}
+		if interval < time.Minute {
+			return nil, fmt.Errorf("every %s is shorter than a minute", j.Every)
+		}
+		return scheduler.Every(interval), nil
+	default:
+		if j.PerDay < 1 || j.PerDay > 24*60 {
+			return nil, fmt.Errorf("perDay %d is outside 1 to %d", j.PerDay, 24*60)
+		}
+		var jitter time.Duration
+		if j.Jitter != "" {
+			var err error
+			if jitter, err = time.ParseDuration(j.Jitter); err != nil {
+				return nil, fmt.Errorf("jitter: %v", err)
+			}
+			if jitter < 0 {
+				return nil, fmt.Errorf("jitter %s must not be negative", j.Jitter)
+			}
+		}
+		return scheduler.PerDay(j.PerDay, jitter, j.Name), nil
+	}
+}
+
+// Validate checks the values of a single profile
+func (p *Profile) Validate() error {
+	if err := p.Voice.validate(); err != nil {
+		return fmt.Errorf("voice.%v", err)
+	}
+	if p.Voice.Fallback != nil {
+		if err := p.Voice.Fallback.validate(); err != nil {
+			return fmt.Errorf("voice.fallback.%v", err)
+		}
+		if p.Voice.Fallback.Fallback != nil {
+			return fmt.Errorf("voice.fallback must not have a fallback of its own")
+		}
+	}
+
+	if len(p.Themes) == 0 {
+		return fmt.Errorf("themes must have at least one entry")
+	}
+	if len(p.Themes) > global.TagLimit {
+		return fmt.Errorf("themes has %d entries, max is %d", len(p.Themes), global.TagLimit)
+	}
+	for _, theme := range p.Themes {
+		if strings.TrimSpace(theme) == "" {
+			return fmt.Errorf("themes contains an empty entry")
+		}
+	}
+
+	if p.Font == "" {
+		return fmt.Errorf("font must be set")
+	}
+	if p.BorderThickness < 0 || p.BorderThickness > 50 {
+		return fmt.Errorf("borderThickness %d is outside 0 to 50", p.BorderThickness)
+	}
+
+	if p.OutputDir == "" {
+		return fmt.Errorf("outputDir must be set")
+	}
+
+	if p.Youtube.Post && p.Youtube.ChannelName == "" {
+		return fmt.Errorf("youtube.channelName must be set when youtube.post is true")
+	}
+	if !contains(privacies, p.Youtube.Privacy) {
+		return fmt.Errorf("youtube.privacy %q is not one of %s", p.Youtube.Privacy, strings.Join(privacies, ", "))
+	}
+	if p.TikTok.Post && p.TikTok.ChannelName == "" {
+		return fmt.Errorf("tiktok.channelName must be set when tiktok.post is true")
+	}
+	if err := p.Reddit.validate(); err != nil {
+		return fmt.Errorf("reddit.%v", err)
+	}
+	if _, err := safety.New(p.Safety.Blocklist); err != nil {
+		return fmt.Errorf("safety.%v", err)
+	}
+	if err := p.Censor.validate(); err != nil {
+		return fmt.Errorf("censor.%v", err)
+	}
+	if err := p.Persona.validate(p.Voice.ProviderName()); err != nil {
+		return fmt.Errorf("persona.%v", err)
+	}
+	return nil
+}
+
+func (v *Voice) validate() error {
+	if v.Provider != "" && !contains(providers, v.Provider) {
+		return fmt.Errorf("provider %q is not one of %s", v.Provider, strings.Join(providers, ", "))
+	}
+	if err := checkVoiceID(v.ProviderName(), v.ID); err != nil {
+		return fmt.Errorf("id %v", err)
+	}
+	if v.Speed < -1 || v.Speed > 1 {
+		return fmt.Errorf("speed %v is outside -1 to 1", v.Speed)
+	}
+	if v.Pitch < 0.5 || v.Pitch > 1.5 {
+		return fmt.Errorf("pitch %v is outside 0.5 to 1.5", v.Pitch)
+	}
+	local := v.ProviderName() == Piper || v.ProviderName() == Espeak
+	if (v.ProviderName() == UnrealSpeech || (local && v.Bitrate != "")) && !contains(bitrates, v.Bitrate) {
+		return fmt.Errorf("bitrate %q is not one of %s", v.Bitrate, strings.Join(bitrates, ", "))
+	}
+	return nil
+}
+
+// googleVoice matches the names of Google voices, which start with their language code, like en-US-Neural2-F
+var googleVoice = regexp.MustCompile(`^[a-z]{2,3}-[A-Z]{2}-\S+$`)
+
+// checkVoiceID checks that a voice of the provider has the id
+func checkVoiceID(provider, id string) error {
+	switch provider {
+	case GoogleTTS:
+		if !googleVoice.MatchString(id) {
+			return fmt.Errorf("%q is not a Google voice name like en-US-Neural2-F", id)
+		}
+		return nil
+	case Piper:
+		if !strings.HasSuffix(id, ".onnx") {
+			return fmt.Errorf("%q is not the path to a piper voice model like voices/en_US-amy-medium.onnx", id)
+		}
+		return nil
+	case Espeak:
+		if id == "" || strings.ContainsFunc(id, unicode.IsSpace) {
+			return fmt.Errorf("%q is not an espeak-ng voice like en-us", id)
+		}
+		return nil
+	}
+	if !contains(voiceIDs, id) {
+		return fmt.Errorf("%q is not one of %s", id, strings.Join(voiceIDs, ", "))
+	}
+	return nil
+}
+
+func (p *Persona) validate(provider string) error {
+	for i, pool := range p.Pools {
+		if pool.Gender != "" && !contains(genders, pool.Gender) {
+			return fmt.Errorf("pools[%d].gender %q is not one of %s", i, pool.Gender, strings.Join(genders, ", "))
+		}
+		if pool.MinAge < 0 || pool.MaxAge < 0 {
+			return fmt.Errorf("pools[%d]: ages must not be negative", i)
+		}
+		if pool.MaxAge > 0 && pool.MaxAge < pool.MinAge {
+			return fmt.Errorf("pools[%d].maxAge %d is below minAge %d", i, pool.MaxAge, pool.MinAge)
+		}
+		if len(pool.Voices) == 0 {
+			return fmt.Errorf("pools[%d].voices must have at least one entry", i)
+		}
+		for _, id := range pool.Voices {
+			if err := checkVoiceID(provider, id); err != nil {
+				return fmt.Errorf("pools[%d].voices: %v", i, err)
+			}
+		}
+	}
+	for _, id := range p.Fallback {
+		if err := checkVoiceID(provider, id); err != nil {
+			return fmt.Errorf("fallback: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *Censor) validate() error {
+	if c.Mode == "" {
+		return nil
+	}
+	if !contains(censorModes, c.Mode) {
+		return fmt.Errorf("mode %q is not one of %s", c.Mode, strings.Join(censorModes, ", "))
+	}
+	if len(c.Words) == 0 {
+		return fmt.Errorf("words must have at least one entry")
+	}
+	if len(strings.Fields(c.Replacement)) > 1 {
+		return fmt.Errorf("replacement %q must be a single word", c.Replacement)
+	}
+	_, err := safety.NewCensor(c.Words)
+	return err
+}
+
+func (r *Reddit) validate() error {
+	if r.Listing != "" && !contains(listings, r.Listing) {
+		return fmt.Errorf("listing %q is not one of %s", r.Listing, strings.Join(listings, ", "))
+	}
+	if r.Time != "" {
+		if r.Listing != "top" {
+			return fmt.Errorf("time is only used by the top listing")
+		}
+		if !contains(topTimes, r.Time) {
+			return fmt.Errorf("time %q is not one of %s", r.Time, strings.Join(topTimes, ", "))
+		}
+	}
+	if r.MinScore < 0 {
+		return fmt.Errorf("minScore %d must not be negative", r.MinScore)
+	}
+	if r.MinUpvoteRatio < 0 || r.MinUpvoteRatio > 1 {
+		return fmt.Errorf("minUpvoteRatio %v is outside 0 to 1", r.MinUpvoteRatio)
+	}
+	if r.MinComments < 0 {
+		return fmt.Errorf("minComments %d must not be negative", r.MinComments)
+	}
+	for name, value := range map[string]int{"comments": r.Comments, "minCommentLength": r.MinCommentLength, "maxCommentLength": r.MaxCommentLength} {
+		if value < 0 {
+			return fmt.Errorf("%s %d must not be negative", name, value)
+		}
+	}
+	if r.MaxCommentLength > 0 && r.MaxCommentLength < r.MinCommentLength {
+		return fmt.Errorf("maxCommentLength %d is shorter than minCommentLength %d", r.MaxCommentLength, r.MinCommentLength)
+	}
+	durations := map[string]time.Duration{}
+	for name, value := range map[string]string{"minDuration": r.MinDuration, "maxDuration": r.MaxDuration} {
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s %s must not be negative", name, value)
+		}
+		durations[name] = d
+	}
+	if max, ok := durations["maxDuration"]; ok && max < durations["minDuration"] {
+		return fmt.Errorf("maxDuration %s is shorter than minDuration %s", r.MaxDuration, r.MinDuration)
+	}
+	return nil
+}
+
+// Durations returns the shortest and longest estimated narration of a post, 0 for no limit
+func (r *Reddit) Durations() (min, max time.Duration) {
+	return parseDuration(r.MinDuration, 0), parseDuration(r.MaxDuration, 0)
+}
+
+// Profile returns the profile with the given name, or the default profile if name is empty
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q (have: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// Pipeline returns the pipeline of a video type
+func (c *Config) Pipeline(name string) (*Pipeline, error) {
+	if pipeline, ok := c.Pipelines[name]; ok {
+		return pipeline, nil
+	}
+	if pipeline, ok := DefaultPipelines[name]; ok {
+		return pipeline, nil
+	}
+	return nil, fmt.Errorf("unknown video type %q (have: %s)", name, strings.Join(c.PipelineNames(), ", "))
+}
+
+// PipelineNames returns the names of all video types in sorted order
+func (c *Config) PipelineNames() []string {
+	var names []string
+	for name := range DefaultPipelines {
+		names = append(names, name)
+	}
+	for name := range c.Pipelines {
+		if _, ok := DefaultPipelines[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a copy of the profile that can be changed for a single run without touching the config
+func (p *Profile) Clone() *Profile {
+	clone := *p
+	clone.Themes = append([]string(nil), p.Themes...)
+	clone.Safety.Blocklist = append([]safety.Rule(nil), p.Safety.Blocklist...)
+	clone.Censor.Words = append([]string(nil), p.Censor.Words...)
+	clone.Persona.Pools = append([]VoicePool(nil), p.Persona.Pools...)
+	clone.Persona.Fallback = append([]string(nil), p.Persona.Fallback...)
+	if p.Voice.Fallback != nil {
+		fallback := *p.Voice.Fallback
+		clone.Voice.Fallback = &fallback
+	}
+	if p.Speech.Expansions != nil {
+		clone.Speech.Expansions = make(map[string]string, len(p.Speech.Expansions))
+		for word, said := range p.Speech.Expansions {
+			clone.Speech.Expansions[word] = said
+		}
+	}
+	return &clone
+}
+
+// ProfileNames returns the names of all profiles in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
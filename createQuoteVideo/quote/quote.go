@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"os"
 	"time"
-	"videoCreater/global"
 )
 
 // Quote struct to match the JSON structure
@@ -25,8 +24,8 @@ type QuotesResponse struct {
 	Total  int     `json:"total"`
 }
 
-// Function that fetches a quote and returns the content and author
-func FetchQuote(thema string) (string, string, error) {
+// Function that fetches a quote and returns the content and author. Quotes for which used returns true are skipped, used may be nil.
+func FetchQuote(thema string, used func(body string) bool) (string, string, error) {
 	// Get the API key from environment variables
 	apiKey := os.Getenv("FAVQS_API_KEY")
 	if apiKey == "" {
@@ -36,6 +35,10 @@ func FetchQuote(thema string) (string, string, error) {
 	// Create a new random generator
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
+	// Pages without a quote that can be used before giving up
+	const maxMisses = 10
+	misses := 0
+
 	for {
 		// Generate a random page number between 1 and 10
 		randomPage := rng.Intn(10) + 1
@@ -84,26 +87,30 @@ func FetchQuote(thema string) (string, string, error) {
 			continue
 		}
 
-		// Select a random quote from the fetched quotes
-		randomQuote := quotesResponse.Quotes[rng.Intn(len(quotesResponse.Quotes))]
-
-		if randomQuote.Body == "No quotes found" {
-			// Remove the current thema from the global list
-			for i, t := range global.Themas {
-				if t == thema {
-					global.Themas = append(global.Themas[:i], global.Themas[i+1:]...)
-					// Return an error stating that no quotes were found for this thema
-					return "", "", fmt.Errorf("no quotes found for the thema: %s", thema)
-				}
+		if quotesResponse.Quotes[0].Body == "No quotes found" {
+			// Return an error stating that no quotes were found for this thema
+			return "", "", fmt.Errorf("no quotes found for the thema: %s", thema)
+		}
+
+		// Only quotes long enough that have not been used before can be picked
+		var candidates []Quote
+		for _, q := range quotesResponse.Quotes {
+			if len(q.Body) >= 50 && (used == nil || !used(q.Body)) {
+				candidates = append(candidates, q)
 			}
 		}
 
-		// Check if the content length is less than or equal to 200 characters
-		if len(randomQuote.Body) >= 50 {
+		if len(candidates) > 0 {
+			// Select a random quote from the fetched quotes
+			randomQuote := candidates[rng.Intn(len(candidates))]
 			return randomQuote.Body, randomQuote.Author, nil
-		} else {
-			log.Println("Selected quote does not meet length criteria. Retrying...")
-			time.Sleep(3 * time.Second)
 		}
+
+		misses++
+		if misses >= maxMisses {
+			return "", "", fmt.Errorf("no unused quotes found for the thema %s in %d pages", thema, misses)
+		}
+		log.Printf("No unused quotes meeting the length criteria on page %d. Retrying...", randomPage)
+		time.Sleep(3 * time.Second)
 	}
 }
@@ -0,0 +1,51 @@
+package reddit
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Author is who wrote a post, as they describe themselves in it
+type Author struct {
+	Gender string `json:"gender"` // m, f or nb
+	Age    int    `json:"age"`
+}
+
+// String returns the author the way Reddit tags them, like 29F
+func (a *Author) String() string {
+	return strconv.Itoa(a.Age) + strings.ToUpper(a.Gender)
+}
+
+// authorPatterns match an age and gender tag after the writer of a post refers to themselves, like I (29F), me 34M or
+// AITA (F29) for. Tags after anyone else, like my (30M) husband, are not the author.
+var authorPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:I|I'm|Im|I am|me|myself|AITA|AITAH|WIBTA|TIFU)[,:]?\s*[(\[]?\s*(\d{1,2})\s*(m|f|nb)\s*[)\]]?(?:\W|$)`),
+	regexp.MustCompile(`(?i)\b(?:I|I'm|Im|I am|me|myself|AITA|AITAH|WIBTA|TIFU)[,:]?\s*[(\[]?\s*(m|f|nb)\s*(\d{1,2})\s*[)\]]?(?:\W|$)`),
+}
+
+// AuthorTag returns how the writer of the post describes themselves in the title or text, or nil if they do not
+func (p *RedditPost) AuthorTag() *Author {
+	for _, text := range []string{p.Title, p.Content} {
+		var best []int
+		var author *Author
+		// The first tag in the text is the one the writer introduces themselves with
+		for i, pattern := range authorPatterns {
+			m := pattern.FindStringSubmatchIndex(text)
+			if m == nil || (best != nil && m[0] > best[0]) {
+				continue
+			}
+			best = m
+			age, gender := text[m[2]:m[3]], text[m[4]:m[5]]
+			if i == 1 {
+				age, gender = gender, age
+			}
+			n, _ := strconv.Atoi(age)
+			author = &Author{Gender: strings.ToLower(gender), Age: n}
+		}
+		if author != nil {
+			return author
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package reddit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultUserAgent is sent when REDDIT_USER_AGENT is not set
+const defaultUserAgent string = "windows:videoCreater:v_test (by /u/Heier420)"
+
+const (
+	publicBaseURL   = "https://www.reddit.com"                     // Unauthenticated JSON endpoints, heavily throttled
+	oauthBaseURL    = "https://oauth.reddit.com"                   // Endpoints for OAuth clients
+	defaultTokenURL = "https://www.reddit.com/api/v1/access_token" // Where app-only tokens are granted
+	maxRetries      = 4                                            // Retries of a request that got 429 or a 5xx
+)
+
+// retryBackoff is how long the first retry of a request waits, every retry after it waits twice as long as the one before
+var retryBackoff = time.Second
+
+// Client calls the Reddit API. With a client id and secret it logs in as an app-only OAuth client, otherwise it uses
+// the public JSON endpoints. It waits when the rate limit Reddit reports is used up, and retries requests that were
+// throttled or failed on Reddit's side. A Client is safe to use from several runs at the same time.
+type Client struct {
+	BaseURL      string // API base URL, without a trailing slash
+	TokenURL     string // Where the OAuth token is requested
+	UserAgent    string
+	ClientID     string // Empty to use the public endpoints
+	ClientSecret string
+	HTTP         *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	remaining   float64   // Requests left in the current rate limit window, -1 if unknown
+	reset       time.Time // When the rate limit window ends
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"` // Seconds
+	Error       string `json:"error"`
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+)
+
+// DefaultClient returns the client used by the functions of this package, made by NewClient the first time it is needed
+func DefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = NewClient()
+		if defaultClient.ClientID == "" {
+			log.Println("REDDIT_CLIENT_ID is not set, using the public Reddit API, which is heavily throttled")
+		}
+	})
+	return defaultClient
+}
+
+// NewClient makes a client from the environment: REDDIT_CLIENT_ID and REDDIT_CLIENT_SECRET are the credentials of the
+// app, REDDIT_USER_AGENT identifies it and REDDIT_BASE_URL and REDDIT_TOKEN_URL point it at another server, like a fake one for testing.
+func NewClient() *Client {
+	c := &Client{
+		BaseURL:      os.Getenv("REDDIT_BASE_URL"),
+		TokenURL:     os.Getenv("REDDIT_TOKEN_URL"),
+		UserAgent:    os.Getenv("REDDIT_USER_AGENT"),
+		ClientID:     os.Getenv("REDDIT_CLIENT_ID"),
+		ClientSecret: os.Getenv("REDDIT_CLIENT_SECRET"),
+		HTTP:         &http.Client{Timeout: 30 * time.Second},
+		remaining:    -1,
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = publicBaseURL
+		if c.ClientID != "" {
+			c.BaseURL = oauthBaseURL
+		}
+	}
+	c.BaseURL = strings.TrimSuffix(c.BaseURL, "/")
+	if c.TokenURL == "" {
+		c.TokenURL = defaultTokenURL
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = defaultUserAgent
+	}
+	return c
+}
+
+// Get requests path from the API and decodes the JSON response into v. Waiting for the rate limit or a retry stops
+// when ctx is done.
+func (c *Client) Get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := time.Duration(math.Pow(2, float64(attempt-1))) * retryBackoff
+			log.Printf("Reddit request failed: %v. Retrying in %s...", lastErr, delay)
+			if err := sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", c.UserAgent)
+		if c.ClientID != "" {
+			token, err := c.accessToken(ctx)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.updateRateLimit(resp.Header)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			if err := json.Unmarshal(body, v); err != nil {
+				return fmt.Errorf("failed to parse response of %s: %v", path, err)
+			}
+			return nil
+		case resp.StatusCode == http.StatusUnauthorized && c.ClientID != "":
+			// The token expired early or was revoked, get a new one
+			c.mu.Lock()
+			c.token = ""
+			c.mu.Unlock()
+			lastErr = fmt.Errorf("unauthorized")
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("status code %d", resp.StatusCode)
+			if wait := retryAfter(resp.Header); wait > 0 {
+				c.mu.Lock()
+				c.remaining = 0
+				c.reset = time.Now().Add(wait)
+				c.mu.Unlock()
+			}
+		default:
+			return fmt.Errorf("unexpected status code %d from %s: %s", resp.StatusCode, path, strings.TrimSpace(string(body)))
+		}
+	}
+	return fmt.Errorf("reddit request %s failed after %d attempts: %v", path, maxRetries+1, lastErr)
+}
+
+// accessToken returns a valid app-only token, requesting a new one when the old one is about to expire
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request a Reddit token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to request a Reddit token: status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse Reddit token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no Reddit token granted: %s", token.Error)
+	}
+
+	// Renew a minute early, so a token never expires halfway through a run
+	c.token = token.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// waitForRateLimit sleeps until the rate limit window ends if no requests are left in it, or until ctx is done
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	c.mu.Lock()
+	var wait time.Duration
+	if c.remaining >= 0 && c.remaining < 1 {
+		wait = time.Until(c.reset)
+	}
+	c.mu.Unlock()
+
+	if wait > 0 {
+		log.Printf("Reddit rate limit used up, waiting %s", wait.Round(time.Second))
+		return sleep(ctx, wait)
+	}
+	return nil
+}
+
+// sleep waits for d, or returns the error of ctx when it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// updateRateLimit records the rate limit Reddit reports in the X-Ratelimit headers
+func (c *Client) updateRateLimit(header http.Header) {
+	remaining, err := strconv.ParseFloat(header.Get("X-Ratelimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseFloat(header.Get("X-Ratelimit-Reset"), 64)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remaining = remaining
+	c.reset = time.Now().Add(time.Duration(reset * float64(time.Second)))
+}
+
+// retryAfter returns how long the Retry-After header asks to wait, 0 if it is not set
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
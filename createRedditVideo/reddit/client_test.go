@@ -0,0 +1,260 @@
+package reddit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	retryBackoff = time.Millisecond
+}
+
+// newTestClient returns a client of the API served by handler. With a client id it gets its tokens from token.
+func newTestClient(t *testing.T, handler http.HandlerFunc, token http.HandlerFunc, clientID string) *Client {
+	api := httptest.NewServer(handler)
+	t.Cleanup(api.Close)
+	c := &Client{
+		BaseURL:      api.URL,
+		TokenURL:     api.URL + "/token",
+		UserAgent:    "test-agent",
+		ClientID:     clientID,
+		ClientSecret: "secret",
+		HTTP:         api.Client(),
+		remaining:    -1,
+	}
+	if token != nil {
+		server := httptest.NewServer(token)
+		t.Cleanup(server.Close)
+		c.TokenURL = server.URL
+	}
+	return c
+}
+
+type listing struct {
+	Kind string `json:"kind"`
+}
+
+func TestClientFetchesToken(t *testing.T) {
+	var tokens, requests atomic.Int32
+	token := func(w http.ResponseWriter, r *http.Request) {
+		tokens.Add(1)
+		id, secret, ok := r.BasicAuth()
+		if r.Method != http.MethodPost || !ok || id != "id" || secret != "secret" {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+			http.Error(w, "bad grant", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token": "token-1", "expires_in": 3600}`))
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get("User-Agent") != "test-agent" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"kind": "Listing"}`))
+	}
+	c := newTestClient(t, handler, token, "id")
+
+	for i := 0; i < 2; i++ {
+		var v listing
+		if err := c.Get(context.Background(), "/r/test/hot.json", nil, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Kind != "Listing" {
+			t.Fatalf("got kind %q, want Listing", v.Kind)
+		}
+	}
+	if tokens.Load() != 1 || requests.Load() != 2 {
+		t.Errorf("got %d token requests and %d requests, want 1 and 2", tokens.Load(), requests.Load())
+	}
+}
+
+func TestClientRenewsRevokedToken(t *testing.T) {
+	var tokens atomic.Int32
+	token := func(w http.ResponseWriter, r *http.Request) {
+		if tokens.Add(1) == 1 {
+			w.Write([]byte(`{"access_token": "revoked", "expires_in": 3600}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "token-2", "expires_in": 3600}`))
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"kind": "Listing"}`))
+	}
+	c := newTestClient(t, handler, token, "id")
+
+	var v listing
+	if err := c.Get(context.Background(), "/r/test/hot.json", nil, &v); err != nil {
+		t.Fatal(err)
+	}
+	if tokens.Load() != 2 {
+		t.Errorf("got %d token requests, want 2", tokens.Load())
+	}
+}
+
+func TestClientTokenError(t *testing.T) {
+	token := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": "invalid_grant"}`))
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {}, token, "id")
+
+	var v listing
+	err := c.Get(context.Background(), "/r/test/hot.json", nil, &v)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("got error %v, want one about invalid_grant", err)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // Status of every request, the last one repeats
+		wantErr  bool
+		wantHits int32
+	}{
+		{"ok", []int{http.StatusOK}, false, 1},
+		{"throttled", []int{http.StatusTooManyRequests, http.StatusOK}, false, 2},
+		{"server errors", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, false, 4},
+		{"always failing", []int{http.StatusServiceUnavailable}, true, maxRetries + 1},
+		{"not found", []int{http.StatusNotFound}, true, 1},
+		{"forbidden", []int{http.StatusForbidden}, true, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var hits atomic.Int32
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				hit := int(hits.Add(1))
+				status := test.statuses[min(hit, len(test.statuses))-1]
+				if status != http.StatusOK {
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+				w.Write([]byte(`{"kind": "Listing"}`))
+			}
+			c := newTestClient(t, handler, nil, "")
+
+			var v listing
+			err := c.Get(context.Background(), "/r/test/hot.json", nil, &v)
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if hits.Load() != test.wantHits {
+				t.Errorf("got %d requests, want %d", hits.Load(), test.wantHits)
+			}
+		})
+	}
+}
+
+func TestClientWaitsForRateLimit(t *testing.T) {
+	const reset = 300 * time.Millisecond
+	var hits atomic.Int32
+	var last atomic.Int64 // When the last request came in, in nanoseconds
+	var gap atomic.Int64  // Time between the first and the second request
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().UnixNano()
+		if hits.Add(1) > 1 {
+			gap.Store(now - last.Load())
+		}
+		last.Store(now)
+		// The first request uses up the window
+		w.Header().Set("X-Ratelimit-Remaining", "0")
+		w.Header().Set("X-Ratelimit-Reset", "0.3")
+		w.Write([]byte(`{"kind": "Listing"}`))
+	}
+	c := newTestClient(t, handler, nil, "")
+
+	for i := 0; i < 2; i++ {
+		var v listing
+		if err := c.Get(context.Background(), "/r/test/hot.json", nil, &v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := time.Duration(gap.Load()); got < reset-50*time.Millisecond {
+		t.Errorf("the second request came %s after the first, want at least %s", got, reset)
+	}
+}
+
+func TestClientHonorsRetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	var first atomic.Int64
+	var gap atomic.Int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			first.Store(time.Now().UnixNano())
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		gap.Store(time.Now().UnixNano() - first.Load())
+		w.Write([]byte(`{"kind": "Listing"}`))
+	}
+	c := newTestClient(t, handler, nil, "")
+
+	var v listing
+	if err := c.Get(context.Background(), "/r/test/hot.json", nil, &v); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(gap.Load()); got < 900*time.Millisecond {
+		t.Errorf("the retry came %s after the first request, want at least 1s", got)
+	}
+}
+
+func TestClientCancelledWhileWaiting(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{"rate limit", map[string]string{"X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": "60"}, http.StatusOK},
+		{"retry after", map[string]string{"Retry-After": "60"}, http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var hits atomic.Int32
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				for key, value := range test.header {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(`{"kind": "Listing"}`))
+			}
+			c := newTestClient(t, handler, nil, "")
+
+			var v listing
+			if test.status == http.StatusOK {
+				if err := c.Get(context.Background(), "/r/test/hot.json", nil, &v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			start := time.Now()
+			err := c.Get(ctx, "/r/test/hot.json", nil, &v)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("got error %v, want %v", err, context.Canceled)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Get returned %s after it was cancelled", elapsed)
+			}
+			if hits.Load() != 1 {
+				t.Errorf("got %d requests, want 1", hits.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package reddit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Comment is a top level comment of a post
+type Comment struct {
+	ID            string `json:"id"`
+	Author        string `json:"author"`
+	Body          string `json:"body"`
+	Score         int    `json:"score"`
+	Stickied      bool   `json:"stickied"`
+	Distinguished string `json:"distinguished"` // moderator or admin if the comment was made as one, empty otherwise
+}
+
+// CommentCriteria decides which comments of a post are narrated
+type CommentCriteria struct {
+	Count     int // How many comments to take, the highest scores first
+	MinLength int // Fewest characters of a comment, 0 for no limit
+	MaxLength int // Most characters of a comment, 0 for no limit
+}
+
+// commentsResponse is the response of /comments/<id>.json: a listing with the post, followed by a listing with the comments
+type commentsResponse []struct {
+	Data struct {
+		Children []struct {
+			Kind string          `json:"kind"` // t1 for comments, more for the link to the rest of them
+			Data json.RawMessage `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// Reject returns why the comment is not narrated, or an empty string if it is
+func (c *CommentCriteria) Reject(comment *Comment) string {
+	body := strings.TrimSpace(comment.Body)
+	switch {
+	case comment.Stickied || comment.Distinguished != "":
+		return "stickied"
+	case body == "" || body == "[removed]" || body == "[deleted]" || comment.Author == "[deleted]":
+		return "removed"
+	case isBot(comment.Author):
+		return "bot"
+	case c.MinLength > 0 && len(body) < c.MinLength:
+		return "too short"
+	case c.MaxLength > 0 && len(body) > c.MaxLength:
+		return "too long"
+	}
+	return ""
+}
+
+// isBot returns true for AutoModerator and accounts named like a bot, like RemindMeBot or auto_bot
+func isBot(author string) bool {
+	name := strings.ToLower(author)
+	return name == "automoderator" || strings.HasSuffix(name, "bot")
+}
+
+// TopComments returns the comments of the post with the highest scores that meet the criteria. Comments for which
+// blocked returns a word are skipped, blocked may be nil.
+func TopComments(postID string, all []Comment, criteria *CommentCriteria, blocked func(text string) string) []Comment {
+	var comments []Comment
+	for _, comment := range all {
+		if criteria.Reject(&comment) != "" {
+			continue
+		}
+		if blocked != nil {
+			if word := blocked(comment.Body); word != "" {
+				log.Printf("Skipping comment %s of post %s: contains the blocked word %q", comment.ID, postID, word)
+				continue
+			}
+		}
+		comments = append(comments, comment)
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].Score > comments[j].Score })
+	if len(comments) > criteria.Count {
+		comments = comments[:criteria.Count]
+	}
+	return comments
+}
+
+// getComments returns the top level comments of the post, the best first
+func getComments(ctx context.Context, subreddit, postID string) ([]Comment, error) {
+	query := url.Values{"sort": {"top"}, "depth": {"1"}, "limit": {"500"}}
+	var result commentsResponse
+	if err := DefaultClient().Get(ctx, fmt.Sprintf("/r/%s/comments/%s.json", subreddit, postID), query, &result); err != nil {
+		return nil, err
+	}
+	if len(result) < 2 {
+		return nil, fmt.Errorf("no comment listing in the response")
+	}
+
+	var comments []Comment
+	for _, child := range result[1].Data.Children {
+		if child.Kind != "t1" {
+			continue
+		}
+		var comment Comment
+		if err := json.Unmarshal(child.Data, &comment); err != nil {
+			return nil, fmt.Errorf("failed to parse comment: %v", err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
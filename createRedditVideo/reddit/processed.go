@@ -0,0 +1,137 @@
+package reddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"videoCreater/filelock"
+)
+
+// ProcessedFile is the name of the file holding the processed posts of every subreddit
+const ProcessedFile string = "reddit-processed.json"
+
+// maxProcessed is how many posts are remembered per subreddit. Older posts are far down the listing and never reached again.
+const maxProcessed = 2000
+
+// Processed is the set of posts of every subreddit that were made into a video, kept in a JSON file.
+// A nil Processed is valid and holds nothing.
+type Processed struct {
+	path string
+
+	mu         sync.Mutex
+	subreddits map[string]map[string]time.Time // Post ids by lowercase subreddit name, with when they were processed
+}
+
+// OpenProcessed reads the processed posts from path. A missing file is an empty set.
+func OpenProcessed(path string) (*Processed, error) {
+	p := &Processed{path: path, subreddits: make(map[string]map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed posts: %v", err)
+	}
+	if err := json.Unmarshal(data, &p.subreddits); err != nil {
+		return nil, fmt.Errorf("failed to parse processed posts %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Has returns true if the post of the subreddit was processed
+func (p *Processed) Has(subreddit, id string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.subreddits[strings.ToLower(subreddit)][id]
+	return ok
+}
+
+// Add marks the post of the subreddit as processed and saves the set. The file is read again under a lock shared with
+// other processes first, so posts processed by other runs since it was opened are kept.
+func (p *Processed) Add(subreddit, id string) error {
+	_, err := p.add(subreddit, id, false)
+	return err
+}
+
+// Claim marks the post of the subreddit as processed like Add, unless another run processed it since the set was
+// opened, and returns whether it did. Of two runs picking the same post at the same time only one gets it.
+func (p *Processed) Claim(subreddit, id string) (bool, error) {
+	return p.add(subreddit, id, true)
+}
+
+// add marks the post as processed and saves the set, unless the post is in the file and claim is set
+func (p *Processed) add(subreddit, id string, claim bool) (bool, error) {
+	if p == nil {
+		return true, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return false, fmt.Errorf("failed to write processed posts: %v", err)
+	}
+	release, err := filelock.Acquire(p.path + ".lock")
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	current, err := OpenProcessed(p.path)
+	if err != nil {
+		return false, err
+	}
+	if claim && current.Has(subreddit, id) {
+		return false, nil
+	}
+	for name, ids := range current.subreddits {
+		if p.subreddits[name] == nil {
+			p.subreddits[name] = make(map[string]time.Time)
+		}
+		for postID, at := range ids {
+			p.subreddits[name][postID] = at
+		}
+	}
+
+	name := strings.ToLower(subreddit)
+	if p.subreddits[name] == nil {
+		p.subreddits[name] = make(map[string]time.Time)
+	}
+	p.subreddits[name][id] = time.Now()
+	trim(p.subreddits[name])
+
+	data, err := json.MarshalIndent(p.subreddits, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal processed posts: %v", err)
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return false, fmt.Errorf("failed to write processed posts: %v", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return false, fmt.Errorf("failed to write processed posts: %v", err)
+	}
+	return true, nil
+}
+
+// trim forgets the oldest posts once there are more than maxProcessed
+func trim(ids map[string]time.Time) {
+	if len(ids) <= maxProcessed {
+		return
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return ids[sorted[i]].Before(ids[sorted[j]]) })
+	for _, id := range sorted[:len(sorted)-maxProcessed] {
+		delete(ids, id)
+	}
+}
@@ -1,19 +1,24 @@
 package reddit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 )
 
-const filename string = "createRedditVideo/last_post_data.json" // File where the last post ID and content are stored
-const userAgent string = "windows:videoCreater:v_test (by /u/Heier420)"
+// How many posts are asked for per page, and how many pages are walked before giving up
+const (
+	pageSize = 100
+	maxPages = 5
+)
 
 type RedditAPIResponse struct {
 	Data struct {
+		After    string `json:"after"` // Name of the last post, to ask for the next page. Empty on the last page.
 		Children []struct {
 			Data RedditPost
 		}
@@ -21,88 +26,197 @@ type RedditAPIResponse struct {
 }
 
 type RedditPost struct {
-	Title   string `json:"title"`
-	ID      string `json:"id"`
-	Content string `json:"selftext"` // This field holds the main body text of the post
+	Title             string  `json:"title"`
+	ID                string  `json:"id"`
+	Content           string  `json:"selftext"` // This field holds the main body text of the post
+	Author            string  `json:"author"`
+	Permalink         string  `json:"permalink"`           // Path of the post on reddit.com
+	URL               string  `json:"url"`                 // The linked page for link posts, the post itself otherwise
+	CreatedUTC        float64 `json:"created_utc"`         // Unix time the post was made
+	Score             int     `json:"score"`               // Upvotes minus downvotes
+	UpvoteRatio       float64 `json:"upvote_ratio"`        // Share of the votes that are upvotes, 0 to 1
+	NumComments       int     `json:"num_comments"`        // How many comments the post has
+	IsSelf            bool    `json:"is_self"`             // True for text posts, false for link, image and video posts
+	Over18            bool    `json:"over_18"`             // Marked NSFW
+	Spoiler           bool    `json:"spoiler"`             // Marked as a spoiler
+	Stickied          bool    `json:"stickied"`            // Announcements pinned by the moderators
+	RemovedByCategory string  `json:"removed_by_category"` // Why the post was removed, like moderator or deleted. Empty if it was not.
+
+	Comments []Comment `json:"comments,omitempty"` // Top comments, only fetched when they are narrated
+	Verdict  *Verdict  `json:"verdict,omitempty"`  // Outcome of an AITA post, only tallied when asked for
 }
 
-func getLatestPost(subreddit string) (*RedditPost, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://www.reddit.com/r/%s/new.json?limit=1", subreddit), nil)
-	if err != nil {
-		return nil, err
-	}
+// Criteria decides which listing posts are taken from and which posts are good enough for a video
+type Criteria struct {
+	Listing        string // new, hot, rising or top. Empty is new.
+	Time           string // Window of the top listing: hour, day, week, month, year or all
+	MinScore       int
+	MinUpvoteRatio float64
+	MinComments    int
+	MinDuration    time.Duration // Shortest estimated narration of the title and text, 0 for no limit
+	MaxDuration    time.Duration // Longest estimated narration of the title and text, 0 for no limit
+
+	AllowNSFW     bool
+	AllowSpoilers bool
+	// Blocked returns a word in the text that rejects the post or comment, or an empty string if there is none. May be nil.
+	Blocked func(text string) string
+
+	// Comments are fetched for posts that are good enough when set. Posts without comments that meet them are skipped,
+	// and posts without text are good enough.
+	Comments *CommentCriteria
+	// Verdict tallies the judgements in the comments of the post that is picked when set. The comments are fetched once
+	// for both.
+	Verdict bool
+}
+
+// wordsPerSecond is how fast a narrator speaks, used to estimate how long a post takes to narrate
+const wordsPerSecond = 2.5
+
+// EstimatedDuration returns about how long narrating the title and text of the post takes
+func (p *RedditPost) EstimatedDuration() time.Duration {
+	words := len(strings.Fields(p.Title)) + len(strings.Fields(p.Content))
+	return time.Duration(float64(words) / wordsPerSecond * float64(time.Second))
+}
 
-	req.Header.Set("User-Agent", userAgent)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// Reject returns why the post can not be made into a video, or an empty string if it can
+func (c *Criteria) Reject(post *RedditPost) string {
+	switch {
+	case post.Stickied:
+		return "stickied"
+	case post.Over18 && !c.AllowNSFW:
+		return "nsfw"
+	case post.Spoiler && !c.AllowSpoilers:
+		return "spoiler"
+	case c.Blocked != nil && c.Blocked(post.Title+"\n"+post.Content) != "":
+		return "blocked"
+	case post.RemovedByCategory != "" || post.Content == "[removed]" || post.Content == "[deleted]" || post.Author == "[deleted]":
+		return "removed"
+	case !post.IsSelf || (c.Comments == nil && strings.TrimSpace(post.Content) == ""):
+		return "no text"
+	case post.Score < c.MinScore:
+		return "score"
+	case post.UpvoteRatio < c.MinUpvoteRatio:
+		return "upvote ratio"
+	case post.NumComments < c.MinComments:
+		return "comments"
+	case c.MinDuration > 0 && post.EstimatedDuration() < c.MinDuration:
+		return "too short"
+	case c.MaxDuration > 0 && post.EstimatedDuration() > c.MaxDuration:
+		return "too long"
 	}
-	defer resp.Body.Close()
+	return ""
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// getPage returns a page of a listing of the subreddit, in the order of the listing, and the after value of the next page
+func getPage(ctx context.Context, subreddit string, criteria *Criteria, after string) ([]RedditPost, string, error) {
+	listing := criteria.Listing
+	if listing == "" {
+		listing = "new"
+	}
+	query := url.Values{"limit": {fmt.Sprint(pageSize)}}
+	if listing == "top" {
+		t := criteria.Time
+		if t == "" {
+			t = "day"
+		}
+		query.Set("t", t)
+	}
+	if after != "" {
+		query.Set("after", after)
 	}
 
 	var result RedditAPIResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+	if err := DefaultClient().Get(ctx, fmt.Sprintf("/r/%s/%s.json", subreddit, listing), query, &result); err != nil {
+		return nil, "", err
 	}
 
-	if len(result.Data.Children) > 0 {
-		return &result.Data.Children[0].Data, nil
+	posts := make([]RedditPost, len(result.Data.Children))
+	for i, child := range result.Data.Children {
+		posts[i] = child.Data
 	}
-	return nil, fmt.Errorf("no posts found")
+	return posts, result.Data.After, nil
 }
 
-func savePostData(post *RedditPost) error {
-	data, err := json.Marshal(post)
-	if err != nil {
-		return err
+// GetRedditPost returns the first post in the listing of the subreddit that meets the criteria, was not processed before
+// and for which used returns false. For the new listing that is the newest such post. It walks down the listing page by
+// page until it finds one, or ctx is done. The post is claimed in processed when it is picked, so runs picking posts at
+// the same time never pick the same one. criteria, processed and used may be nil.
+func GetRedditPost(ctx context.Context, subreddit string, criteria *Criteria, processed *Processed, used func(id string) bool) (*RedditPost, error) {
+	if criteria == nil {
+		criteria = &Criteria{}
 	}
-	return os.WriteFile(filename, data, 0644)
-}
 
-func readPostData() (*RedditPost, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-	var post RedditPost
-	if err := json.Unmarshal(data, &post); err != nil {
-		return nil, err
-	}
-	return &post, nil
-}
+	after := ""
+	seen := 0
+	rejected := make(map[string]int) // How many posts were skipped for every reason
+	for page := 0; page < maxPages; page++ {
+		posts, next, err := getPage(ctx, subreddit, criteria, after)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching post: %v", err)
+		}
+		seen += len(posts)
+
+		for i := range posts {
+			post := &posts[i]
+			if processed.Has(subreddit, post.ID) || (used != nil && used(post.ID)) {
+				rejected["processed"]++
+				continue
+			}
+			if reason := criteria.Reject(post); reason != "" {
+				rejected[reason]++
+				logUnsafe(subreddit, post, reason, criteria)
+				continue
+			}
+			if criteria.Comments != nil || criteria.Verdict {
+				comments, err := getComments(ctx, subreddit, post.ID)
+				if err != nil {
+					return nil, fmt.Errorf("error fetching comments of post %s: %v", post.ID, err)
+				}
+				if criteria.Comments != nil {
+					post.Comments = TopComments(post.ID, comments, criteria.Comments, criteria.Blocked)
+					if len(post.Comments) == 0 {
+						rejected["no comments"]++
+						continue
+					}
+				}
+				if criteria.Verdict {
+					post.Verdict = Tally(comments)
+				}
+			}
+			claimed, err := processed.Claim(subreddit, post.ID)
+			if err != nil {
+				return nil, fmt.Errorf("error claiming post %s: %v", post.ID, err)
+			}
+			if !claimed {
+				rejected["processed"]++ // By another run since the processed posts were read
+				continue
+			}
+			return post, nil
+		}
 
-func GetRedditPost(subreddit string) (*RedditPost, error) {
-	// Check if the file exists, create if it does not
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		// Create an empty file if it does not exist
-		if err := os.WriteFile(filename, []byte("{}"), 0644); err != nil {
-			return nil, fmt.Errorf("failed to create initial data file: %v", err)
+		if next == "" {
+			break
 		}
+		after = next
 	}
 
-	lastPost, err := readPostData()
-	if err != nil {
-		return nil, fmt.Errorf("error reading last post data: %v", err)
+	if seen == 0 {
+		return nil, fmt.Errorf("no posts found")
 	}
-
-	post, err := getLatestPost(subreddit)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching post: %v", err)
+	var reasons []string
+	for reason, count := range rejected {
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
 	}
+	sort.Strings(reasons)
+	return nil, fmt.Errorf("no new posts in the first %d posts of r/%s that meet the criteria (skipped: %s)", seen, subreddit, strings.Join(reasons, ", "))
+}
 
-	// Check if the fetched post is new compared to the last saved post
-	if lastPost == nil || post.ID != lastPost.ID {
-		if err := savePostData(post); err != nil {
-			log.Printf("Error saving last post data: %v", err)
-		}
-		return post, nil // Return the new post
+// logUnsafe logs why a post was skipped when it was for safety, so it shows up in the run log
+func logUnsafe(subreddit string, post *RedditPost, reason string, criteria *Criteria) {
+	switch reason {
+	case "nsfw", "spoiler":
+		log.Printf("Skipping post %s of r/%s: marked %s", post.ID, subreddit, reason)
+	case "blocked":
+		log.Printf("Skipping post %s of r/%s: contains the blocked word %q", post.ID, subreddit, criteria.Blocked(post.Title+"\n"+post.Content))
 	}
-
-	// Return nil if there is no new post
-	return nil, fmt.Errorf("no new posts")
 }
@@ -0,0 +1,86 @@
+package reddit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Judgements are the verdicts commenters give on AITA posts, with what they stand for
+var Judgements = map[string]string{
+	"YTA":  "You're the A-hole",
+	"NTA":  "Not the A-hole",
+	"ESH":  "Everyone sucks here",
+	"NAH":  "No A-holes here",
+	"INFO": "Not enough info",
+}
+
+// judgementPattern finds a judgement written as a word of its own. Only upper case counts, so "info" in a sentence does not.
+var judgementPattern = regexp.MustCompile(`\b(YTA|NTA|ESH|NAH|INFO)\b`)
+
+// Verdict is the outcome of an AITA post: the share of the votes of every judgement, weighted by the score of the comments
+type Verdict struct {
+	Judgement string             `json:"judgement"` // The judgement with the largest share
+	Shares    map[string]float64 `json:"shares"`    // Share of every judgement that was given, 0 to 1
+	Comments  int                `json:"comments"`  // How many comments gave a judgement
+}
+
+// Share is a judgement and its share of the votes
+type Share struct {
+	Judgement string
+	Share     float64
+}
+
+// Tally counts the first judgement of every comment, weighted by its score. Removed comments and bots are not counted,
+// and comments with a score below 1 count as 1. It returns nil if no comment gave a judgement.
+func Tally(comments []Comment) *Verdict {
+	criteria := &CommentCriteria{}
+	weights := make(map[string]float64)
+	var total float64
+	verdict := &Verdict{Shares: make(map[string]float64)}
+	for i := range comments {
+		if criteria.Reject(&comments[i]) != "" {
+			continue
+		}
+		match := judgementPattern.FindString(comments[i].Body)
+		if match == "" {
+			continue
+		}
+		weight := float64(comments[i].Score)
+		if weight < 1 {
+			weight = 1
+		}
+		weights[match] += weight
+		total += weight
+		verdict.Comments++
+	}
+	if verdict.Comments == 0 {
+		return nil
+	}
+
+	for judgement, weight := range weights {
+		verdict.Shares[judgement] = weight / total
+	}
+	verdict.Judgement = verdict.Sorted()[0].Judgement
+	return verdict
+}
+
+// Sorted returns the judgements that were given, the largest share first
+func (v *Verdict) Sorted() []Share {
+	var shares []Share
+	for judgement, share := range v.Shares {
+		shares = append(shares, Share{Judgement: judgement, Share: share})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share != shares[j].Share {
+			return shares[i].Share > shares[j].Share
+		}
+		return shares[i].Judgement < shares[j].Judgement
+	})
+	return shares
+}
+
+// Percent returns the share of the winning judgement as a whole percentage, like "82%"
+func (v *Verdict) Percent() string {
+	return fmt.Sprintf("%.0f%%", v.Shares[v.Judgement]*100)
+}
@@ -0,0 +1,282 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"videoCreater/config"
+	"videoCreater/voice"
+
+	"golang.org/x/oauth2"
+)
+
+// MinFreeDiskSpace is the free space needed to download the raw footage and render a video
+const MinFreeDiskSpace uint64 = 2 << 30 // 2 GiB
+
+// Result is the outcome of a single check
+type Result struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// Features selects which parts of the bot are checked
+type Features struct {
+	Quote  bool // Quote videos: FavQs, Pexels and the narrator
+	Reddit bool // Reddit videos: Reddit, YouTube search and the narrator
+}
+
+// Run runs every check needed for the features and the publish targets enabled in the profile. Runs keep their files in workspaceDir
+// and record what they used in contentHistoryDir.
+func Run(profile *config.Profile, workspaceDir, contentHistoryDir string, features Features) []Result {
+	var results []Result
+
+	results = append(results, checkEnv(profile, features)...)
+	results = append(results, checkFFmpeg()...)
+	if features.Quote || features.Reddit {
+		results = append(results, checkLocalEngines(profile.Voice)...)
+	}
+	results = append(results, checkFile("font", profile.Font))
+	if profile.Voice.Lexicon != "" {
+		results = append(results, checkLexicon(profile.Voice.Lexicon))
+	}
+
+	for _, dir := range []string{workspaceDir, contentHistoryDir, profile.OutputDir} {
+		results = append(results, checkWritable(dir))
+	}
+
+	if profile.Youtube.Post {
+		results = append(results, checkFile("YouTube client secret", "client_secret.json"))
+		results = append(results, checkToken("token.json"))
+	}
+
+	results = append(results, checkDiskSpace(workspaceDir))
+	return results
+}
+
+// Failed returns true if any of the results failed
+func Failed(results []Result) bool {
+	for _, result := range results {
+		if !result.OK {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEnv checks the environment variables every enabled feature reads
+func checkEnv(profile *config.Profile, features Features) []Result {
+	required := map[string][]string{}
+	add := func(feature string, names ...string) {
+		for _, name := range names {
+			required[name] = append(required[name], feature)
+		}
+	}
+
+	if features.Quote {
+		add("quote", "FAVQS_API_KEY", "PEXELS_API_KEY")
+	}
+	if features.Reddit {
+		add("reddit", "REDDIT_USER_AGENT", "YOUTUBE_API_KEY")
+	}
+	narrates := features.Quote || features.Reddit
+	if narrates && profile.Voice.ProviderName() == config.UnrealSpeech {
+		add("narrator", "UNREAL_SPEECH_API_KEY")
+	}
+	if fallback := profile.Voice.Fallback; narrates && fallback != nil && fallback.ProviderName() == config.UnrealSpeech {
+		add("narrator fallback", "UNREAL_SPEECH_API_KEY")
+	}
+	if profile.TikTok.Post {
+		add("tiktok upload", "TIKTOK_CLIENT_KEY")
+	}
+
+	names := []string{"FAVQS_API_KEY", "PEXELS_API_KEY", "UNREAL_SPEECH_API_KEY", "YOUTUBE_API_KEY", "REDDIT_USER_AGENT", "TIKTOK_CLIENT_KEY"}
+	var results []Result
+	for _, name := range names {
+		neededBy, ok := required[name]
+		if !ok {
+			continue
+		}
+		result := Result{Name: "env " + name, OK: os.Getenv(name) != ""}
+		if result.OK {
+			result.Detail = "set"
+		} else {
+			result.Detail = fmt.Sprintf("not set, needed by %s", strings.Join(neededBy, ", "))
+		}
+		results = append(results, result)
+	}
+
+	if narrates && usesGoogle(profile.Voice) {
+		results = append(results, checkGoogleCredentials())
+	}
+
+	// The Reddit credentials are optional, without them the public API is used
+	if features.Reddit && (os.Getenv("REDDIT_CLIENT_ID") == "") != (os.Getenv("REDDIT_CLIENT_SECRET") == "") {
+		results = append(results, Result{Name: "env REDDIT_CLIENT_ID", Detail: "REDDIT_CLIENT_ID and REDDIT_CLIENT_SECRET must be set together"})
+	}
+	return results
+}
+
+// usesGoogle returns whether the voice or its fallback is narrated by Google
+func usesGoogle(v config.Voice) bool {
+	return v.ProviderName() == config.GoogleTTS || (v.Fallback != nil && v.Fallback.ProviderName() == config.GoogleTTS)
+}
+
+// checkGoogleCredentials checks that Google Text-to-Speech has an API key or a service account to authenticate with
+func checkGoogleCredentials() Result {
+	const name = "Google Text-to-Speech credentials"
+	if os.Getenv("GOOGLE_TTS_API_KEY") != "" {
+		return Result{Name: name, OK: true, Detail: "GOOGLE_TTS_API_KEY set"}
+	}
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return Result{Name: name, Detail: "neither GOOGLE_TTS_API_KEY nor GOOGLE_APPLICATION_CREDENTIALS is set"}
+	}
+	return checkFile(name, path)
+}
+
+// checkLocalEngines checks that the offline engines the voice or its fallback narrate with are installed, and that
+// the piper voice models can be read
+func checkLocalEngines(v config.Voice) []Result {
+	voices := []config.Voice{v}
+	if v.Fallback != nil {
+		voices = append(voices, *v.Fallback)
+	}
+	var results []Result
+	for _, v := range voices {
+		var engine string
+		switch v.ProviderName() {
+		case config.Piper:
+			engine = "piper"
+			results = append(results, checkFile("piper voice model", v.ID))
+		case config.Espeak:
+			engine = "espeak-ng"
+		default:
+			continue
+		}
+		if path, err := exec.LookPath(engine); err != nil {
+			results = append(results, Result{Name: engine, Detail: fmt.Sprintf("not found: %v", err)})
+		} else {
+			results = append(results, Result{Name: engine, OK: true, Detail: path})
+		}
+	}
+	return results
+}
+
+// checkFFmpeg checks that ffmpeg and ffprobe can be run and that ffmpeg has the filters editVideo uses
+func checkFFmpeg() []Result {
+	var results []Result
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		output, err := exec.Command(tool, "-version").Output()
+		if err != nil {
+			results = append(results, Result{Name: tool, Detail: fmt.Sprintf("not found: %v", err)})
+			continue
+		}
+		version := strings.SplitN(string(output), "\n", 2)[0]
+		results = append(results, Result{Name: tool, OK: true, Detail: version})
+	}
+	if !results[0].OK {
+		return results
+	}
+
+	output, err := exec.Command("ffmpeg", "-hide_banner", "-filters").Output()
+	if err != nil {
+		return append(results, Result{Name: "ffmpeg filters", Detail: fmt.Sprintf("failed to list filters: %v", err)})
+	}
+	available := map[string]bool{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			available[fields[1]] = true
+		}
+	}
+	for _, filter := range []string{"drawtext", "overlay", "scale", "crop", "trim"} {
+		result := Result{Name: "ffmpeg filter " + filter, OK: available[filter], Detail: "available"}
+		if !result.OK {
+			result.Detail = "missing, ffmpeg has to be built with it"
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkFile checks that a file exists and can be read
+func checkFile(name, path string) Result {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{Name: name, Detail: err.Error()}
+	}
+	f.Close()
+	return Result{Name: name, OK: true, Detail: path}
+}
+
+// checkLexicon checks that the pronunciation lexicon can be read
+func checkLexicon(path string) Result {
+	lexicon, err := voice.LoadLexicon(path)
+	if err != nil {
+		return Result{Name: "lexicon", Detail: err.Error()}
+	}
+	return Result{Name: "lexicon", OK: true, Detail: fmt.Sprintf("%s, %d words", path, len(lexicon))}
+}
+
+// checkWritable checks that a file can be created in dir, creating dir if it does not exist
+func checkWritable(dir string) Result {
+	name := "dir " + dir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Result{Name: name, Detail: fmt.Sprintf("failed to create: %v", err)}
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return Result{Name: name, Detail: fmt.Sprintf("not writable: %v", err)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return Result{Name: name, OK: true, Detail: "writable"}
+}
+
+// checkToken checks the cached YouTube OAuth token. An expired token is fine as long as it can be refreshed.
+func checkToken(path string) Result {
+	name := "YouTube token"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{Name: name, Detail: fmt.Sprintf("%v, run an upload by hand once to authorize the bot", err)}
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return Result{Name: name, Detail: fmt.Sprintf("failed to parse %s: %v", path, err)}
+	}
+
+	switch {
+	case token.AccessToken == "" && token.RefreshToken == "":
+		return Result{Name: name, Detail: fmt.Sprintf("%s holds no token", path)}
+	case token.Expiry.IsZero() || token.Expiry.After(time.Now()):
+		return Result{Name: name, OK: true, Detail: fmt.Sprintf("valid until %s", token.Expiry.Format(time.RFC3339))}
+	case token.RefreshToken != "":
+		return Result{Name: name, OK: true, Detail: fmt.Sprintf("expired %s, will be refreshed", token.Expiry.Format(time.RFC3339))}
+	default:
+		return Result{Name: name, Detail: fmt.Sprintf("expired %s and has no refresh token, delete %s and authorize again", token.Expiry.Format(time.RFC3339), path)}
+	}
+}
+
+// checkDiskSpace checks that there is enough free space where the videos are written
+func checkDiskSpace(dir string) Result {
+	name := "disk space"
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return Result{Name: name, Detail: err.Error()}
+	}
+	free, err := freeDiskSpace(path)
+	if err != nil {
+		return Result{Name: name, Detail: fmt.Sprintf("failed to check: %v", err)}
+	}
+	detail := fmt.Sprintf("%.1f GiB free", float64(free)/(1<<30))
+	if free < MinFreeDiskSpace {
+		return Result{Name: name, Detail: fmt.Sprintf("%s, need at least %.1f GiB", detail, float64(MinFreeDiskSpace)/(1<<30))}
+	}
+	return Result{Name: name, OK: true, Detail: detail}
+}
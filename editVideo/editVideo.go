@@ -13,12 +13,13 @@ import (
 	voice "videoCreater/voice"
 )
 
-// escapeText escapes the text to be used in ffmpeg drawtext filter
+// escapeText escapes the text to be used in ffmpeg drawtext filter as text='...'. The text passes three levels of
+// escaping: drawtext expands % and \, the option value ends at : and ', and the filtergraph quotes it with '.
 func escapeText(text string) string {
-	text = strings.ReplaceAll(text, ":", "\\:")
-	text = strings.ReplaceAll(text, "'", "'\\''")
 	text = strings.ReplaceAll(text, "\n", "")
-	return text
+	text = strings.NewReplacer(`\`, `\\`, `%`, `\%`).Replace(text)
+	text = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(text)
+	return strings.ReplaceAll(text, "'", `'\''`)
 }
 
 // findNextAvailableFilename finds the next available filename with the given prefix
@@ -109,25 +110,28 @@ func removeSpaces(input string) string {
 	return strings.ReplaceAll(input, " ", "")
 }
 
+// splitTitleIntoLines wraps the title in lines of about maxLength characters. Line breaks in the title are kept.
 func splitTitleIntoLines(title string, maxLength int) []string {
 	var lines []string
-	words := strings.Fields(title)
-	var currentLine string
+	for _, paragraph := range strings.Split(title, "\n") {
+		words := strings.Fields(paragraph)
+		var currentLine string
+
+		for _, word := range words {
+			if currentLine != "" && len(currentLine)+len(word)+1 > maxLength {
+				lines = append(lines, currentLine)
+				currentLine = word + " "
+			} else {
+				currentLine += word + " "
+			}
+		}
 
-	for _, word := range words {
-		if len(currentLine)+len(word)+1 > maxLength {
+		// Add the last line if it's not empty
+		if currentLine != "" {
 			lines = append(lines, currentLine)
-			currentLine = word + " "
-		} else {
-			currentLine += word + " "
 		}
 	}
 
-	// Add the last line if it's not empty
-	if currentLine != "" {
-		lines = append(lines, currentLine)
-	}
-
 	// If there are more than 4 lines, truncate and add "..."
 	if len(lines) > 4 {
 		lines = lines[:4]
@@ -1,18 +1,20 @@
 package editVideo
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"videoCreater/global"
+	"videoCreater/config"
 	voice "videoCreater/voice"
 )
 
-// EditVideoTikTok creates TikTok-style videos with text overlays from input video and audio files.
-func EditVideoTikTok(inputVideoPath string, inputAudioPaths []string, wordTimings [][]voice.WordInfo, title string) ([]string, error) {
+// EditVideoTikTok creates TikTok-style videos with text overlays from input video and audio files, with titles[i] on top of
+// the video of the i-th audio file. The videos and the logo are written to dir. ffmpeg is stopped if ctx is cancelled.
+func EditVideoTikTok(ctx context.Context, inputVideoPath string, inputAudioPaths []string, wordTimings [][]voice.WordInfo, titles []string, profile *config.Profile, dir string) ([]string, error) {
 	const fontSize = 110
 
 	titleFontSize := 110
@@ -24,11 +26,11 @@ func EditVideoTikTok(inputVideoPath string, inputAudioPaths []string, wordTiming
 	var elapsedTime float64
 
 	// Specify the path to the font file
-	fontPath := "fonts/PermanentMarker-Regular.ttf"
+	fontPath := profile.Font
 
 	// TikTok logo image
 	tikTokLogoURL := "https://cdn4.iconfinder.com/data/icons/social-media-flat-7/64/Social-media_Tiktok-512.png"
-	tikTokLogoPath := filepath.Join("logos", "tiktok_logo.png")
+	tikTokLogoPath := filepath.Join(dir, "tiktok_logo.png")
 	err := DownloadImage(tikTokLogoURL, tikTokLogoPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download YouTube logo: %v", err)
@@ -38,22 +40,23 @@ func EditVideoTikTok(inputVideoPath string, inputAudioPaths []string, wordTiming
 	defer os.Remove(tikTokLogoPath)
 
 	for i := range inputAudioPaths {
+		title := titles[i]
 
 		// Escape text for FFmpeg
 		words, timingStrings, endTime := splitTextIntoWordsWithTimings(wordTimings[i])
 
 		// Determine the output video path
-		outputDir := "edited-videos"
 		partSuffix := ""
 		if len(inputAudioPaths) > 1 {
 			partSuffix = fmt.Sprintf("Part%dof%d", i+1, len(inputAudioPaths))
 		}
 
-		shortTitle := title
-		if len(title) > 25 {
-			shortTitle = title[:25]
+		// Every part is named after the first title, so the parts of a video stay together
+		shortTitle := titles[0]
+		if len(shortTitle) > 25 {
+			shortTitle = shortTitle[:25]
 		}
-		outputFilename := findNextAvailableFilename(outputDir, removeSpaces(shortTitle+partSuffix), ".mp4")
+		outputFilename := findNextAvailableFilename(dir, removeSpaces(shortTitle+partSuffix), ".mp4")
 
 		titleLines := splitTitleIntoLines(title, charsPerLine)
 
@@ -63,24 +66,24 @@ func EditVideoTikTok(inputVideoPath string, inputAudioPaths []string, wordTiming
 		for j, line := range titleLines {
 			drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 				"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=50+(%d*%.0f):fontsize=%d:fontcolor=white:borderw=%d:bordercolor=black",
-				fontPath, escapeText(line), j, lineHeight, titleFontSize, global.BorderThickness))
+				fontPath, escapeText(line), j, lineHeight, titleFontSize, profile.BorderThickness))
 		}
 		// Part text under the title in the middle
 		if partSuffix != "" {
 			drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 				"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=h-th-200:fontsize=80:fontcolor=white:borderw=%d:bordercolor=black",
-				fontPath, escapeText(fmt.Sprintf("part %v of %v", i+1, len(inputAudioPaths))), global.BorderThickness))
+				fontPath, escapeText(fmt.Sprintf("part %v of %v", i+1, len(inputAudioPaths))), profile.BorderThickness))
 		}
 		// Words centered in the middle of the screen
 		for j, word := range words {
 			drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 				"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=(h/2-text_h/2):fontsize=100:fontcolor=white:borderw=%d:bordercolor=black:enable='%s'",
-				fontPath, escapeText(word), global.BorderThickness, timingStrings[j]))
+				fontPath, escapeText(word), profile.BorderThickness, timingStrings[j]))
 		}
 		// Channel name text at the bottom of the screen
 		drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 			"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=h-th-50:fontsize=%d:fontcolor=white:borderw=%d:bordercolor=black",
-			fontPath, global.TikTokChannelName, fontSize, global.BorderThickness))
+			fontPath, escapeText(profile.TikTok.ChannelName), fontSize, profile.BorderThickness))
 
 		// Adds the tiktok logo to the video
 		filterComplex := fmt.Sprintf(
@@ -88,7 +91,7 @@ func EditVideoTikTok(inputVideoPath string, inputAudioPaths []string, wordTiming
 			elapsedTime+60, strings.Join(drawtextFilters, ","), fontSize) // Add 60 seconds to the elapsed time
 
 		// Write filter complex to a temporary file
-		filterFile, err := os.CreateTemp("", "ffmpeg-filter-*.txt")
+		filterFile, err := os.CreateTemp(dir, "ffmpeg-filter-*.txt")
 		if err != nil {
 			return nil, fmt.Errorf("failed to create temp file: %v", err)
 		}
@@ -118,7 +121,7 @@ func EditVideoTikTok(inputVideoPath string, inputAudioPaths []string, wordTiming
 		}
 
 		// FFmpeg command for creating the video with text overlays and adding audio
-		cmd := exec.Command("ffmpeg", cmdArgs...)
+		cmd := exec.CommandContext(ctx, "ffmpeg", cmdArgs...)
 
 		// Run FFmpeg command
 		output, err := cmd.CombinedOutput()
@@ -1,16 +1,19 @@
 package editVideo
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"videoCreater/global"
+	"videoCreater/config"
 	voice "videoCreater/voice"
 )
 
-func EditVideoYoutube(inputVideoPath string, inputAudioPath string, wordTimings []voice.WordInfo, title string, author string) (string, error) {
+// EditVideoYoutube creates a YouTube short of a quote with the words shown as they are spoken. The video and the logo are written to dir.
+// ffmpeg is stopped if ctx is cancelled.
+func EditVideoYoutube(ctx context.Context, inputVideoPath string, inputAudioPath string, wordTimings []voice.WordInfo, title string, author string, profile *config.Profile, dir string) (string, error) {
 	authorText := fmt.Sprintf("- %s", abbreviateAuthorName(author))
 	fontSize := 100         // Set the font size for the author text
 	lineHeight := 110 * 1.2 // Set the line height for the title text
@@ -21,16 +24,15 @@ func EditVideoYoutube(inputVideoPath string, inputAudioPath string, wordTimings
 		return "", err
 	}
 
-	// Escape text for FFmpeg
-	escapedAuthorText := escapeText(authorText)
-	words, timingStrings := splitTextIntoWordsWithTimingsWithAuthor(wordTimings, escapedAuthorText, audioDuration)
+	// The author is shown after the last word, and escaped with the words
+	words, timingStrings := splitTextIntoWordsWithTimingsWithAuthor(wordTimings, authorText, audioDuration)
 
 	// Specify the path to the font file
-	fontPath := "fonts/PermanentMarker-Regular.ttf"
+	fontPath := profile.Font
 
 	// Download the YouTube logo image
 	youtubeLogoURL := "https://upload.wikimedia.org/wikipedia/commons/e/ef/Youtube_logo.png"
-	youtubeLogoPath := filepath.Join("logos", "youtube_logo.png")
+	youtubeLogoPath := filepath.Join(dir, "youtube_logo.png")
 	err = DownloadImage(youtubeLogoURL, youtubeLogoPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to download YouTube logo: %v", err)
@@ -40,8 +42,7 @@ func EditVideoYoutube(inputVideoPath string, inputAudioPath string, wordTimings
 	defer os.Remove(youtubeLogoPath)
 
 	// Determine the output video path
-	outputDir := "edited-videos"
-	outputFilename := findNextAvailableFilename(outputDir, removeSpaces(title), ".mp4")
+	outputFilename := findNextAvailableFilename(dir, removeSpaces(title), ".mp4")
 
 	titleLines := splitTitleIntoLines(title, 15)
 	// Build drawtext filters for the content lines
@@ -50,18 +51,18 @@ func EditVideoYoutube(inputVideoPath string, inputAudioPath string, wordTimings
 	for i, line := range titleLines {
 		drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 			"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=50+(%d*%.0f):fontsize=110:fontcolor=white:borderw=%d:bordercolor=black",
-			fontPath, escapeText(line), i, lineHeight, global.BorderThickness))
+			fontPath, escapeText(line), i, lineHeight, profile.BorderThickness))
 	}
 	// Words centered in the middle of the screen
 	for i, word := range words {
 		drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 			"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=(h/2-text_h/2):fontsize=100:fontcolor=white:borderw=%d:bordercolor=black:enable='%s'",
-			fontPath, escapeText(word), global.BorderThickness, timingStrings[i]))
+			fontPath, escapeText(word), profile.BorderThickness, timingStrings[i]))
 	}
 	// Channel name text at the bottom of the screen
 	drawtextFilters = append(drawtextFilters, fmt.Sprintf(
 		"drawtext=fontfile='%s':text='%s':x=(w-text_w)/2:y=h-th-50:fontsize=%d:fontcolor=white:borderw=%d:bordercolor=black",
-		fontPath, global.YoutubeChannelName, fontSize, global.BorderThickness))
+		fontPath, escapeText(profile.Youtube.ChannelName), fontSize, profile.BorderThickness))
 
 	// Adds the logo image to the video
 	filterComplex := fmt.Sprintf(
@@ -85,7 +86,7 @@ func EditVideoYoutube(inputVideoPath string, inputAudioPath string, wordTimings
 	}
 
 	// FFmpeg command for creating the video with text overlays and adding audio
-	cmd := exec.Command("ffmpeg", cmdArgs...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", cmdArgs...)
 
 	// Run FFmpeg command
 	output, err := cmd.CombinedOutput()
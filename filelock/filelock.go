@@ -0,0 +1,24 @@
+// Package filelock locks files across processes, so processes sharing a file can take turns writing it
+package filelock
+
+import (
+	"fmt"
+	"os"
+)
+
+// Acquire waits until the lock file at path is locked for this process only, creating it if needed. Files that are
+// replaced when they are rewritten cannot hold the lock themselves, so they are locked through a file next to them.
+func Acquire(path string) (release func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %v", path, err)
+	}
+	if err := Lock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+	return func() {
+		Unlock(f)
+		f.Close()
+	}, nil
+}
@@ -8,12 +8,21 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
+// Video is a downloaded video and where it came from
+type Video struct {
+	ID   string // Id of the video on the site it was downloaded from
+	URL  string // Page of the video
+	Path string // Where the video was saved
+}
+
 // PexelsVideoResponse represents the response structure from Pexels API
 type PexelsVideoResponse struct {
 	Videos []struct {
-		Id         int `json:"id"`
+		Id         int    `json:"id"`
+		URL        string `json:"url"`
 		VideoFiles []struct {
 			Link   string `json:"link"`
 			Width  int    `json:"width"`
@@ -22,18 +31,20 @@ type PexelsVideoResponse struct {
 	} `json:"videos"`
 }
 
-// FetchAndStoreVideos fetches a specified number of videos from Pexels based on the theme and stores them in the raw-videos folder
-func FetchAndStoreVideosPexels(theme string, amount int) ([]string, error) {
+// FetchAndStoreVideos fetches a specified number of videos from Pexels based on the theme and stores them in dir.
+// Videos for which used returns true are skipped, used may be nil.
+func FetchAndStoreVideosPexels(theme string, amount int, dir string, used func(id string) bool) ([]Video, error) {
 	apiKey := os.Getenv("PEXELS_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("PEXELS_API_KEY environment variable is not set")
 	}
 
-	var videoPaths []string
+	var videos []Video
 	seenIDs := make(map[int]bool) // To avoid downloading duplicates
 
 	for i := 0; i < amount; i++ {
-		url := fmt.Sprintf("https://api.pexels.com/videos/search?query=%s&per_page=%d", theme, amount)
+		// Every request asks for the next page, so videos that were skipped are replaced by new ones
+		url := fmt.Sprintf("https://api.pexels.com/videos/search?query=%s&per_page=%d&page=%d", theme, amount, i+1)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %v", err)
@@ -72,16 +83,19 @@ func FetchAndStoreVideosPexels(theme string, amount int) ([]string, error) {
 				continue // Skip if already processed this video
 			}
 			seenIDs[video.Id] = true
+			if used != nil && used(strconv.Itoa(video.Id)) {
+				continue // Skip videos the channel used before
+			}
 
 			for _, file := range video.VideoFiles {
 				if file.Width == 1920 && file.Height == 1080 {
-					videoPath, err := downloadAndSaveVideo(file.Link, video.Id)
+					videoPath, err := downloadAndSaveVideo(file.Link, video.Id, dir)
 					if err != nil {
 						log.Printf("Failed to download video: %v", err)
 						continue
 					}
-					videoPaths = append(videoPaths, videoPath)
-					if len(videoPaths) >= amount {
+					videos = append(videos, Video{ID: strconv.Itoa(video.Id), URL: video.URL, Path: videoPath})
+					if len(videos) >= amount {
 						break // Stop if we have enough videos
 					}
 				}
@@ -89,16 +103,15 @@ func FetchAndStoreVideosPexels(theme string, amount int) ([]string, error) {
 		}
 	}
 
-	if len(videoPaths) < amount {
-		return videoPaths, fmt.Errorf("only found %d videos out of requested %d", len(videoPaths), amount)
+	if len(videos) < amount {
+		return videos, fmt.Errorf("only found %d videos out of requested %d", len(videos), amount)
 	}
-	return videoPaths, nil
+	return videos, nil
 }
 
-func downloadAndSaveVideo(url string, id int) (string, error) {
-	dir := "raw-videos"
+func downloadAndSaveVideo(url string, id int, dir string) (string, error) {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.Mkdir(dir, 0755); err != nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
 			return "", fmt.Errorf("failed to create directory: %v", err)
 		}
 	}
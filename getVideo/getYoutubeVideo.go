@@ -126,8 +126,8 @@ func parseISO8601Duration(isoDuration string) (time.Duration, error) {
 	return time.ParseDuration(isoDuration)
 }
 
-// DownloadVideo downloads a video from YouTube and saves it locally
-func DownloadVideo(videoURL string) (string, error) {
+// DownloadVideo downloads a video from YouTube and saves it in saveDir
+func DownloadVideo(videoURL string, saveDir string) (string, error) {
 	client := youtube.Client{}
 
 	video, err := client.GetVideo(videoURL)
@@ -135,9 +135,8 @@ func DownloadVideo(videoURL string) (string, error) {
 		return "", fmt.Errorf("failed to get video info: %v", err)
 	}
 
-	saveDir := "raw-videos"
 	if _, err := os.Stat(saveDir); os.IsNotExist(err) {
-		if err := os.Mkdir(saveDir, 0755); err != nil {
+		if err := os.MkdirAll(saveDir, 0755); err != nil {
 			return "", fmt.Errorf("failed to create directory: %v", err)
 		}
 	}
@@ -162,8 +161,9 @@ func DownloadVideo(videoURL string) (string, error) {
 	return filePath, nil
 }
 
-// FetchAndDownloadYoutubeVideo fetches and downloads a single gameplay video from YouTube that fits the duration range
-func FetchAndDownloadYoutubeVideo(query string, minDuration, maxDuration int) (string, error) {
+// FetchAndDownloadYoutubeVideo fetches and downloads a single gameplay video from YouTube that fits the duration range into dir.
+// Videos for which used returns true are skipped, used may be nil.
+func FetchAndDownloadYoutubeVideo(query string, minDuration, maxDuration int, dir string, used func(id string) bool) (Video, error) {
 	const maxRetries = 5
 	var lastError error
 
@@ -179,6 +179,9 @@ func FetchAndDownloadYoutubeVideo(query string, minDuration, maxDuration int) (s
 		var suitableVideos []string
 		for _, videoURL := range videoURLs {
 			videoID := strings.TrimPrefix(videoURL, "https://www.youtube.com/watch?v=")
+			if used != nil && used(videoID) {
+				continue // Skip videos the channel used before
+			}
 			duration, err := getVideoDuration(videoID)
 			if err != nil {
 				log.Printf("Attempt %d: Failed to get duration for video %s: %v", attempt, videoURL, err)
@@ -201,7 +204,7 @@ func FetchAndDownloadYoutubeVideo(query string, minDuration, maxDuration int) (s
 		rand.Seed(time.Now().UnixNano())
 		selectedVideoURL := suitableVideos[rand.Intn(len(suitableVideos))]
 
-		videoPath, err := DownloadVideo(selectedVideoURL)
+		videoPath, err := DownloadVideo(selectedVideoURL, dir)
 		if err != nil {
 			lastError = err
 			log.Printf("Attempt %d: Failed to download video %s: %v", attempt, selectedVideoURL, err)
@@ -209,8 +212,9 @@ func FetchAndDownloadYoutubeVideo(query string, minDuration, maxDuration int) (s
 			continue
 		}
 
-		return videoPath, nil
+		videoID := strings.TrimPrefix(selectedVideoURL, "https://www.youtube.com/watch?v=")
+		return Video{ID: videoID, URL: selectedVideoURL, Path: videoPath}, nil
 	}
 
-	return "", lastError
+	return Video{}, lastError
 }
@@ -13,7 +13,7 @@ if [ "$current_head" != "$new_head" ]; then
     # Update the go.mod file to ensure the Go version is in the format 1.x
     sed -i -E 's/^go ([0-9]+\.[0-9]+)\.[0-9]+$/go \1/' go.mod
 
-    go build -o videomaker main.go
+    go build -o videomaker .
     chmod +x videomaker
 fi
 
@@ -0,0 +1,11 @@
+package global
+
+/**
+DO NOT CHANGE
+Everything that can be tuned lives in the config file, see the config package.
+*/
+
+// The Youtube # limit
+const TagLimit int = 15
+
+const MaxVoiceCharacters int = 1500
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"videoCreater/history"
+	"videoCreater/pipeline"
+)
+
+func runHistory(args []string) error {
+	fs := newFlagSet("history", "[list|show <run id>]", "Lists past runs with what they were made from, or shows the full manifest of a run.\nUse --search to trace a video back to a Reddit post, a quote or the footage it used.")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	videoType := fs.String("type", "", "only list runs of this video type")
+	profile := fs.String("profile", "", "only list runs made with this profile")
+	status := fs.String("status", "", "only list runs that succeeded or failed")
+	since := fs.String("since", "", "only list runs started in the last duration, like 24h, or since a date, like 2024-01-31")
+	search := fs.String("search", "", "only list runs whose manifest contains the text, like a post id, a footage id or a YouTube video id")
+	limit := fs.Int("limit", 20, "how many runs to list, 0 lists every run")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	h, err := history.Open(cfg.HistoryDir())
+	if err != nil {
+		return err
+	}
+
+	action := "list"
+	if len(positional) > 0 {
+		action = positional[0]
+	}
+	switch action {
+	case "list":
+		if len(positional) > 1 {
+			fs.Usage()
+			return fmt.Errorf("history list takes no arguments")
+		}
+		filter := history.Filter{Type: *videoType, Profile: *profile, Status: pipeline.Status(*status), Search: *search, Limit: *limit}
+		if *status != "" && filter.Status != pipeline.Succeeded && filter.Status != pipeline.Failed {
+			return fmt.Errorf("unknown status %q. Use 'succeeded' or 'failed'", *status)
+		}
+		if *since != "" {
+			if filter.Since, err = parseSince(*since); err != nil {
+				return err
+			}
+		}
+
+		runs, err := h.List(filter)
+		if err != nil {
+			return err
+		}
+		printRuns(runs)
+		return nil
+
+	case "show":
+		if len(positional) != 2 {
+			fs.Usage()
+			return fmt.Errorf("history show requires the run id")
+		}
+		m, err := h.Get(positional[1])
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown history action %q", action)
+	}
+}
+
+// parseSince parses a duration back from now or a date
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q, use a duration like 24h or a date like 2024-01-31", value)
+}
+
+// printRuns prints one line per run with the sources and the published ids
+func printRuns(runs []*pipeline.Manifest) {
+	if len(runs) == 0 {
+		fmt.Println("No runs")
+		return
+	}
+	for _, m := range runs {
+		var footage, published []string
+		for _, f := range m.Footage {
+			footage = append(footage, f.Provider+":"+f.ID)
+		}
+		for _, video := range m.Videos {
+			if video.PublishedID != "" {
+				published = append(published, video.Platform+":"+video.PublishedID)
+			}
+		}
+
+		detail := fmt.Sprintf("footage %s", strings.Join(footage, ","))
+		if len(published) > 0 {
+			detail += fmt.Sprintf("  published %s", strings.Join(published, ","))
+		}
+		if m.Bundle != "" {
+			detail += "  bundle " + m.Bundle
+		}
+		if m.Error != "" {
+			detail = strings.SplitN(m.Error, "\n", 2)[0]
+		}
+		fmt.Printf("%-20s %s %-10s %-8s %-12s %-24s %s\n", m.RunID, m.StartedAt.Format(time.DateTime), m.Status, m.Type, m.Profile, contentSummary(m.Content), detail)
+	}
+}
+
+// contentSummary names what a run was made from, like a Reddit post id or the author of a quote
+func contentSummary(content interface{}) string {
+	fields, ok := content.(map[string]interface{})
+	if !ok {
+		return "-"
+	}
+	if id, ok := fields["id"].(string); ok && id != "" {
+		return "post " + id
+	}
+	if author, ok := fields["author"].(string); ok && author != "" {
+		return "quote by " + author
+	}
+	return "-"
+}
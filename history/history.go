@@ -0,0 +1,143 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"videoCreater/pipeline"
+)
+
+// LogFile is the name of the history log, one manifest per line
+const LogFile string = "history.jsonl"
+
+// RunsDir is the directory holding the manifest of every run, named after the run id
+const RunsDir string = "runs"
+
+// Log is the history of every run: a manifest file per run and an append only log of all of them
+type Log struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Filter selects runs from the history. Empty fields match every run.
+type Filter struct {
+	Type    string
+	Profile string
+	Status  pipeline.Status
+	Since   time.Time
+	Search  string // Text anywhere in the manifest, like a Reddit post id or a footage id
+	Limit   int    // Most recent runs to return, 0 returns every run
+}
+
+// Open opens the history in dir, creating dir if needed
+func Open(dir string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Join(dir, RunsDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+	return &Log{dir: dir}, nil
+}
+
+// Record writes the manifest of a run and appends it to the log
+func (l *Log) Record(m *pipeline.Manifest) error {
+	if err := m.Write(filepath.Join(l.dir, RunsDir, m.RunID+".json")); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(l.dir, LogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history log: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history log: %v", err)
+	}
+	return nil
+}
+
+// List returns the runs in the log that match the filter, newest first
+func (l *Log) List(filter Filter) ([]*pipeline.Manifest, error) {
+	f, err := os.Open(filepath.Join(l.dir, LogFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history log: %v", err)
+	}
+	defer f.Close()
+
+	var runs []*pipeline.Manifest
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if filter.Search != "" && !strings.Contains(strings.ToLower(scanner.Text()), strings.ToLower(filter.Search)) {
+			continue
+		}
+		var m pipeline.Manifest
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return nil, fmt.Errorf("failed to parse history log line %d: %v", line, err)
+		}
+		if filter.matches(&m) {
+			runs = append(runs, &m)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history log: %v", err)
+	}
+
+	// The log is in the order runs finished, newest last
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	if filter.Limit > 0 && len(runs) > filter.Limit {
+		runs = runs[:filter.Limit]
+	}
+	return runs, nil
+}
+
+// Get returns the manifest of a run
+func (l *Log) Get(id string) (*pipeline.Manifest, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid run id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(l.dir, RunsDir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("run %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	var m pipeline.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return &m, nil
+}
+
+func (f *Filter) matches(m *pipeline.Manifest) bool {
+	if f.Type != "" && m.Type != f.Type {
+		return false
+	}
+	if f.Profile != "" && m.Profile != f.Profile {
+		return false
+	}
+	if f.Status != "" && m.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && m.StartedAt.Before(f.Since) {
+		return false
+	}
+	return true
+}
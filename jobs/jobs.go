@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/history"
+	"videoCreater/pipeline"
+	"videoCreater/progress"
+	"videoCreater/seen"
+	"videoCreater/workspace"
+)
+
+// Request describes a video to make. Every field but Type is optional and overrides the profile for this run only.
+type Request struct {
+	Type      string `json:"type"`                // Video type: quote, reddit or a pipeline from the config
+	Subreddit string `json:"subreddit,omitempty"` // Required for reddit videos
+	Theme     string `json:"theme,omitempty"`     // Theme of a quote video
+	Profile   string `json:"profile,omitempty"`   // Defaults to the config's default profile
+	Voice     string `json:"voice,omitempty"`     // Overrides voice.id
+	OutputDir string `json:"outputDir,omitempty"` // Overrides outputDir
+	Youtube   *bool  `json:"youtube,omitempty"`   // Overrides youtube.post
+	TikTok    *bool  `json:"tiktok,omitempty"`    // Overrides tiktok.post
+	KeepFiles bool   `json:"keepFiles,omitempty"` // Keep the videos after they have been published
+	DryRun    bool   `json:"dryRun,omitempty"`    // Write a bundle instead of publishing
+
+	KeepWorkspace bool `json:"keepWorkspace,omitempty"` // Keep the intermediate files of the run for debugging
+}
+
+// Resolve checks the request and returns a copy of its profile with the overrides applied
+func (r *Request) Resolve(cfg *config.Config) (*config.Profile, error) {
+	p, err := pipeline.New(cfg, r.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Source.Check(r.input()); err != nil {
+		return nil, err
+	}
+
+	base, err := cfg.Profile(r.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := base.Clone()
+	if r.Theme != "" {
+		profile.Themes = []string{r.Theme}
+	}
+	if r.Voice != "" {
+		profile.Voice.ID = r.Voice
+		profile.Persona = config.Persona{} // Every post is narrated in the voice that was asked for
+	}
+	if r.OutputDir != "" {
+		profile.OutputDir = r.OutputDir
+	}
+	if r.Youtube != nil {
+		profile.Youtube.Post = *r.Youtube
+	}
+	if r.TikTok != nil {
+		profile.TikTok.Post = *r.TikTok
+	}
+	if r.KeepFiles || r.DryRun {
+		profile.Youtube.DeleteAfterPost = false
+		profile.TikTok.DeleteAfterPost = false
+	}
+
+	// The overrides have to follow the same rules as the config file
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid overrides for profile %q: %v", profile.Name, err)
+	}
+	return profile, nil
+}
+
+// Execute makes the video described by the request in a workspace of its own, recording the progress of each stage
+// in tracker, which may be nil. The workspace is removed afterwards, whether the run succeeded, failed or panicked.
+// The manifest of the run is added to the history, and to the bundle of a dry run.
+// The outputs of every finished stage are checkpointed under the run id, so executing a failed run again with the
+// same id continues after the last stage that finished. The checkpoint is removed when the run succeeds.
+func Execute(ctx context.Context, cfg *config.Config, id string, req Request, tracker *progress.Tracker) (*bundle.Bundle, error) {
+	profile, err := req.Resolve(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pipeline.New(cfg, req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := workspace.New(cfg.WorkspaceDir(), id, cfg.Workspace.Keep || req.KeepWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := ws.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	manifest := &pipeline.Manifest{
+		RunID:     id,
+		Type:      req.Type,
+		Profile:   profile.Name,
+		Input:     req.input(),
+		DryRun:    req.DryRun,
+		StartedAt: time.Now(),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			manifest.Finish(fmt.Errorf("panic: %v", r))
+			recordRun(cfg, manifest)
+			panic(r)
+		}
+	}()
+
+	cp, err := openCheckpoint(cfg, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := seen.Open(cfg.ContentHistoryDir(), cfg.ContentWindow())
+	if err != nil {
+		return nil, err
+	}
+
+	run := &pipeline.Run{ID: id, Input: req.input(), Profile: profile, Workspace: ws, Manifest: manifest, Checkpoint: cp, Seen: used}
+	b, err := p.Run(ctx, run, req.DryRun, tracker)
+	manifest.Finish(err)
+	recordRun(cfg, manifest)
+	if err == nil {
+		if err := cp.Remove(); err != nil {
+			log.Println(err)
+		}
+	}
+	return b, err
+}
+
+// openCheckpoint returns the checkpoint of an earlier attempt of the run, or a new one
+func openCheckpoint(cfg *config.Config, id string, req Request) (*pipeline.Checkpoint, error) {
+	dir := filepath.Join(cfg.CheckpointDir(), id)
+	cp, err := pipeline.LoadCheckpoint(dir)
+	if err == nil {
+		log.Printf("Resuming run %s after stage %s", id, cp.Last())
+		return cp, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load checkpoint of run %s: %v", id, err)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	return pipeline.NewCheckpoint(dir, id, data), nil
+}
+
+// LoadRequest returns the request a checkpointed run was started with, so it can be resumed
+func LoadRequest(cp *pipeline.Checkpoint) (Request, error) {
+	var req Request
+	if err := json.Unmarshal(cp.Request, &req); err != nil {
+		return req, fmt.Errorf("failed to parse request of run %s: %v", cp.RunID, err)
+	}
+	return req, nil
+}
+
+// recordRun adds the manifest to the history and to the bundle of a dry run. Failing to do so does not fail the run.
+func recordRun(cfg *config.Config, manifest *pipeline.Manifest) {
+	if manifest.Bundle != "" {
+		if err := manifest.Write(filepath.Join(manifest.Bundle, pipeline.ManifestFile)); err != nil {
+			log.Println(err)
+		}
+	}
+	h, err := history.Open(cfg.HistoryDir())
+	if err == nil {
+		err = h.Record(manifest)
+	}
+	if err != nil {
+		log.Printf("Failed to record run %s in the history: %v", manifest.RunID, err)
+	}
+}
+
+// input returns what the request asks of the content source
+func (r *Request) input() pipeline.Input {
+	return pipeline.Input{Subreddit: r.Subreddit, Theme: r.Theme}
+}
+
+// NewID returns a new id for a run, made from the current time and a random suffix
+func NewID() string {
+	return fmt.Sprintf("%s-%04x", time.Now().Format("20060102-150405"), rand.Intn(0x10000))
+}
+
+// artifacts returns the paths of the videos in the bundle
+func artifacts(b *bundle.Bundle) []string {
+	if b == nil {
+		return nil
+	}
+	var paths []string
+	for _, video := range b.Videos {
+		path := video.File
+		if b.Dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(b.Dir, path)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
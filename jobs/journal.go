@@ -0,0 +1,328 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"videoCreater/filelock"
+)
+
+// JournalFile is the name of the journal in the queue directory
+const JournalFile string = "jobs.jsonl"
+
+// runningDir is the directory next to the journal holding a lock file for every job running in any process
+const runningDir string = "running"
+
+// lockFileName is the name of the file every process locks while it writes the journal, next to the journal. The journal
+// itself is replaced when it is compacted, so it cannot hold the lock.
+const lockFileName string = "jobs.lock"
+
+// Journal is an append only JSON-lines file holding a snapshot of a job every time its state changes.
+// The highest version of a job wins. Other processes, like the queue command, may append to it while a daemon is running.
+type Journal struct {
+	path     string
+	lockPath string
+
+	mu     sync.Mutex
+	offset int64 // How far ReadNew has read
+}
+
+type record struct {
+	Time time.Time `json:"time"`
+	Job  Job       `json:"job"`
+}
+
+// OpenJournal opens the journal in dir, creating dir if needed
+func OpenJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %v", err)
+	}
+	return &Journal{path: filepath.Join(dir, JournalFile), lockPath: filepath.Join(dir, lockFileName)}, nil
+}
+
+// lock keeps every other goroutine and process from writing the journal until unlock is called
+func (j *Journal) lock() (unlock func(), err error) {
+	j.mu.Lock()
+	release, err := filelock.Acquire(j.lockPath)
+	if err != nil {
+		j.mu.Unlock()
+		return nil, fmt.Errorf("failed to lock journal: %v", err)
+	}
+	return func() {
+		release()
+		j.mu.Unlock()
+	}, nil
+}
+
+// Append writes the current state of a job to the end of the journal
+func (j *Journal) Append(job Job) error {
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return j.append(job)
+}
+
+// append writes a job to the end of the journal. The journal has to be locked.
+func (j *Journal) append(job Job) error {
+	data, err := json.Marshal(record{Time: time.Now(), Job: job})
+	if err != nil {
+		return fmt.Errorf("failed to marshal job %s: %v", job.ID, err)
+	}
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	return nil
+}
+
+// ReadAll returns the latest state of every job in the journal, oldest job first
+func (j *Journal) ReadAll() ([]Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records, offset, err := j.read(0)
+	if err != nil {
+		return nil, err
+	}
+	j.offset = offset
+	return latest(records), nil
+}
+
+// ReadNew returns the jobs written since the last ReadAll, ReadNew or Compact, in the order they were written
+func (j *Journal) ReadNew() ([]Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records, offset, err := j.read(j.offset)
+	if err != nil {
+		return nil, err
+	}
+	j.offset = offset
+	jobs := make([]Job, len(records))
+	for i, r := range records {
+		jobs[i] = r.Job
+	}
+	return jobs, nil
+}
+
+// Compact rewrites the journal to hold only the jobs keep returns of the latest state of every job, and returns them.
+// The journal stays locked from reading to replacing it, so no job another process appends in between is lost.
+func (j *Journal) Compact(keep func(jobs []Job) []Job) ([]Job, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	records, _, err := j.read(0)
+	if err != nil {
+		return nil, err
+	}
+	jobs := keep(latest(records))
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".jobs-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact journal: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	now := time.Now()
+	for _, job := range jobs {
+		data, err := json.Marshal(record{Time: now, Job: job})
+		if err != nil {
+			tmp.Close()
+			return nil, fmt.Errorf("failed to marshal job %s: %v", job.ID, err)
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to compact journal: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compact journal: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return nil, fmt.Errorf("failed to compact journal: %v", err)
+	}
+
+	info, err := os.Stat(j.path)
+	if err != nil {
+		return nil, err
+	}
+	j.offset = info.Size()
+	return jobs, nil
+}
+
+// Claim appends a job that is about to run and keeps other processes from running it until release is called. When the
+// journal holds a newer version of the job, like when another process claimed it first, or another process runs it,
+// the job is not claimed: release is nil and saved is the latest version of the job in the journal.
+func (j *Journal) Claim(job Job) (release func(), saved Job, err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, Job{}, err
+	}
+	defer unlock()
+
+	records, _, err := j.read(0)
+	if err != nil {
+		return nil, Job{}, err
+	}
+	saved = job
+	for _, s := range latest(records) {
+		if s.ID == job.ID && s.Version >= job.Version {
+			return nil, s, nil
+		}
+		if s.ID == job.ID {
+			saved = s
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(j.path), runningDir), 0755); err != nil {
+		return nil, Job{}, fmt.Errorf("failed to claim job %s: %v", job.ID, err)
+	}
+	f, err := os.OpenFile(j.runningPath(job.ID), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, Job{}, fmt.Errorf("failed to claim job %s: %v", job.ID, err)
+	}
+	if locked, err := filelock.TryLock(f); !locked {
+		f.Close()
+		if err != nil {
+			return nil, Job{}, fmt.Errorf("failed to claim job %s: %v", job.ID, err)
+		}
+		return nil, saved, nil
+	}
+	if err := j.append(job); err != nil {
+		filelock.Unlock(f)
+		f.Close()
+		return nil, Job{}, err
+	}
+
+	return func() {
+		// The file is removed under the journal lock, so no process locks it after it is gone
+		if unlock, err := j.lock(); err == nil {
+			defer unlock()
+		}
+		filelock.Unlock(f)
+		f.Close()
+		os.Remove(f.Name())
+	}, job, nil
+}
+
+// runningElsewhere returns whether another process holds the claim on a job. The journal has to be locked.
+func (j *Journal) runningElsewhere(id string) bool {
+	f, err := os.OpenFile(j.runningPath(id), os.O_RDWR, 0644)
+	if err != nil {
+		return false
+	}
+	locked, err := filelock.TryLock(f)
+	if locked {
+		filelock.Unlock(f)
+	}
+	f.Close()
+	if locked {
+		os.Remove(f.Name()) // Nobody runs the job, the process that claimed it died
+	}
+	return !locked && err == nil
+}
+
+// runningPath returns the path of the lock file held while a job runs
+func (j *Journal) runningPath(id string) string {
+	return filepath.Join(filepath.Dir(j.path), runningDir, id+".lock")
+}
+
+// read reads the complete lines after offset and returns the offset after the last of them. j.mu has to be held.
+func (j *Journal) read(offset int64) ([]record, int64, error) {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, fmt.Errorf("failed to read journal: %v", err)
+	}
+
+	var records []record
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without a newline is still being written, it is read next time
+			return records, offset, nil
+		}
+		if err != nil {
+			return records, offset, fmt.Errorf("failed to read journal: %v", err)
+		}
+		offset += int64(len(line))
+
+		// A line torn by a process that died while writing it is skipped, or it would stop every later line from being read
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			log.Printf("Skipping journal line at byte %d: %v", offset-int64(len(line)), err)
+			continue
+		}
+		records = append(records, r)
+	}
+}
+
+// latest returns the highest version of each job, sorted by when the job was created
+func latest(records []record) []Job {
+	byID := make(map[string]Job)
+	for _, r := range records {
+		if current, ok := byID[r.Job.ID]; !ok || r.Job.Version >= current.Version {
+			byID[r.Job.ID] = r.Job
+		}
+	}
+	jobs := make([]Job, 0, len(byID))
+	for _, job := range byID {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].CreatedAt.Before(jobs[b].CreatedAt) })
+	return jobs
+}
+
+// Requeue appends a dead or cancelled job to the journal again with its attempts reset.
+// A running daemon picks it up from the journal, otherwise it runs on the next start.
+func (j *Journal) Requeue(id string) (Job, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return Job{}, err
+	}
+	defer unlock()
+
+	records, _, err := j.read(0)
+	if err != nil {
+		return Job{}, err
+	}
+	for _, job := range latest(records) {
+		if job.ID != id {
+			continue
+		}
+		if job.State != Dead && job.State != Cancelled {
+			return Job{}, fmt.Errorf("job %s is %s, only dead or cancelled jobs can be requeued", id, job.State)
+		}
+		resetForRequeue(&job)
+		job.Version++
+		return job, j.append(job)
+	}
+	return Job{}, fmt.Errorf("job %s not found", id)
+}
@@ -0,0 +1,529 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/progress"
+)
+
+// State is the state of a job
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Retrying  State = "retrying" // Failed, waiting for the next attempt
+	Succeeded State = "succeeded"
+	Dead      State = "dead" // Failed and out of attempts, can be requeued
+	Cancelled State = "cancelled"
+)
+
+// watchInterval is how often the journal is checked for jobs added or requeued by other processes
+const watchInterval = 5 * time.Second
+
+// Job is a single run of the pipeline
+type Job struct {
+	ID            string                 `json:"id"`
+	Version       int                    `json:"version"` // Goes up by one on every change, the highest version in the journal wins
+	Request       Request                `json:"request"`
+	State         State                  `json:"state"`
+	Attempts      int                    `json:"attempts"`
+	Error         string                 `json:"error,omitempty"`
+	FailedStage   progress.Stage         `json:"failedStage,omitempty"`
+	NextAttemptAt *time.Time             `json:"nextAttemptAt,omitempty"`
+	Stages        []progress.StageStatus `json:"stages"`
+	Bundle        string                 `json:"bundle,omitempty"`    // Directory of the bundle of a dry run
+	Artifacts     []string               `json:"artifacts,omitempty"` // Paths of the rendered videos
+	CreatedAt     time.Time              `json:"createdAt"`
+	StartedAt     *time.Time             `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time             `json:"finishedAt,omitempty"`
+
+	tracker *progress.Tracker  // Progress of the current attempt, nil between attempts
+	cancel  context.CancelFunc // Set before an unfinished job is put in the map, nil if another process runs the job
+}
+
+// Finished returns true if the job will not run again unless it is requeued
+func (j *Job) Finished() bool {
+	return j.State == Succeeded || j.State == Dead || j.State == Cancelled
+}
+
+// Manager runs jobs with at most a fixed number running at the same time. Failed jobs are retried following the
+// queue's retry policy. With a journal every change is written to disk, so the queue survives a restart.
+type Manager struct {
+	cfg     *config.Config
+	slot    chan struct{}
+	keep    int
+	journal *Journal
+
+	stop     chan struct{} // Closed on shutdown, jobs that have not started stay queued
+	stopOnce sync.Once
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a manager that runs at most concurrency jobs at the same time and remembers the last keep
+// succeeded or cancelled jobs. Dead jobs are always remembered. journal may be nil to keep the jobs in memory only.
+func NewManager(cfg *config.Config, concurrency, keep int, journal *Journal) *Manager {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Manager{
+		cfg:     cfg,
+		slot:    make(chan struct{}, concurrency),
+		keep:    keep,
+		journal: journal,
+		stop:    make(chan struct{}),
+		jobs:    make(map[string]*Job),
+	}
+}
+
+// Start resumes the unfinished jobs in the journal and watches it for jobs requeued by other processes until ctx is done.
+// Jobs that were running when the process running them stopped are started again, jobs another process is running
+// are followed.
+func (m *Manager) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		m.stopOnce.Do(func() { close(m.stop) })
+	}()
+	if m.journal == nil {
+		return nil
+	}
+
+	saved, err := m.journal.Compact(func(saved []Job) []Job {
+		for i := range saved {
+			if saved[i].State == Running && !m.journal.runningElsewhere(saved[i].ID) {
+				log.Printf("Job %s: was interrupted, queueing it again", saved[i].ID)
+				saved[i].State = Queued
+				saved[i].Version++
+			}
+		}
+		return prune(saved, m.keep)
+	})
+	if err != nil {
+		return err
+	}
+	for _, job := range saved {
+		m.merge(job)
+	}
+
+	go m.watch(ctx)
+	return nil
+}
+
+// Submit queues a job and returns right away
+func (m *Manager) Submit(req Request) (Job, error) {
+	if _, err := req.Resolve(m.cfg); err != nil {
+		return Job{}, err
+	}
+
+	// The job can be cancelled as soon as it is in the map
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:        NewID(),
+		Request:   req,
+		State:     Queued,
+		CreatedAt: time.Now(),
+		cancel:    cancel,
+	}
+	m.mu.Lock()
+	for m.jobs[job.ID] != nil {
+		job.ID = NewID()
+	}
+	m.jobs[job.ID] = job
+	m.mu.Unlock()
+
+	snapshot := m.update(job, func() {})
+	m.start(ctx, job)
+	return snapshot, nil
+}
+
+// Get returns the job with the given id
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return snapshot(job), true
+}
+
+// List returns up to limit jobs, newest first. A limit of 0 returns every job. An empty state returns jobs in any state.
+func (m *Manager) List(limit int, state State) []Job {
+	m.mu.Lock()
+	var list []Job
+	for _, job := range m.jobs {
+		if state == "" || job.State == state {
+			list = append(list, snapshot(job))
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// Cancel stops a job that has not finished. A running job stops at the next stage, or right away while rendering.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	if job.Finished() {
+		return fmt.Errorf("job %s already %s", id, job.State)
+	}
+	if job.cancel == nil {
+		return fmt.Errorf("job %s is run by another process", id)
+	}
+	job.cancel()
+	return nil
+}
+
+// Requeue queues a dead or cancelled job again with its attempts reset. The job is checked and queued under the lock,
+// so requeueing it twice at the same time starts it once.
+func (m *Manager) Requeue(id string) (Job, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("job %s not found", id)
+	}
+	if job.State != Dead && job.State != Cancelled {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("job %s is %s, only dead or cancelled jobs can be requeued", id, job.State)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	resetForRequeue(job)
+	job.cancel = cancel
+	s := m.changedLocked(job)
+	m.mu.Unlock()
+
+	m.save(s)
+	m.start(ctx, job)
+	return s, nil
+}
+
+// Shutdown stops starting jobs and waits for the running ones to finish. Waiting jobs stay queued in the journal.
+func (m *Manager) Shutdown() {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.wg.Wait()
+}
+
+// watch picks up jobs written to the journal by other processes
+func (m *Manager) watch(ctx context.Context) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		saved, err := m.journal.ReadNew()
+		if err != nil {
+			log.Printf("Failed to read the job journal: %v", err)
+			continue
+		}
+		for _, job := range saved {
+			m.merge(job)
+		}
+	}
+}
+
+// merge takes over a job read from the journal if it is new or newer than the job in memory, unless this process runs
+// the job. A running job is run by another process and only followed.
+func (m *Manager) merge(saved Job) {
+	m.mu.Lock()
+	current, ok := m.jobs[saved.ID]
+	if ok && (saved.Version <= current.Version || (!current.Finished() && current.cancel != nil)) {
+		m.mu.Unlock()
+		return
+	}
+	job := saved
+	var ctx context.Context
+	if !job.Finished() && job.State != Running {
+		ctx, job.cancel = context.WithCancel(context.Background())
+	}
+	m.jobs[job.ID] = &job
+	m.mu.Unlock()
+
+	if ctx != nil {
+		log.Printf("Job %s: %s from the journal", job.ID, job.State)
+		m.start(ctx, &job)
+	}
+}
+
+// start runs the job in the background until ctx, whose cancel the job holds, is done
+func (m *Manager) start(ctx context.Context, job *Job) {
+	m.wg.Add(1)
+	go m.run(ctx, job)
+}
+
+// run makes attempts until the job succeeds, is cancelled, runs out of attempts or the manager shuts down
+func (m *Manager) run(ctx context.Context, job *Job) {
+	defer m.wg.Done()
+
+	for {
+		// Wait for the next attempt
+		m.mu.Lock()
+		next := job.NextAttemptAt
+		m.mu.Unlock()
+		if next != nil {
+			timer := time.NewTimer(time.Until(*next))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				m.update(job, func() { finish(job, Cancelled, ctx.Err()) })
+				return
+			case <-m.stop:
+				timer.Stop()
+				return
+			}
+		}
+
+		// Wait for a free slot
+		select {
+		case m.slot <- struct{}{}:
+		case <-ctx.Done():
+			m.update(job, func() { finish(job, Cancelled, ctx.Err()) })
+			return
+		case <-m.stop:
+			return
+		}
+
+		tracker := progress.NewTracker()
+		release, claimed := m.claim(job, func() {
+			now := time.Now()
+			job.State = Running
+			job.Attempts++
+			job.StartedAt = &now
+			job.NextAttemptAt = nil
+			job.Error = ""
+			job.FailedStage = ""
+			job.tracker = tracker
+		})
+		if !claimed {
+			<-m.slot
+			return
+		}
+		log.Printf("Job %s: attempt %d of %s", job.ID, job.Attempts, job.Request.Type)
+
+		b, err := m.execute(ctx, job.ID, job.Request, tracker)
+		<-m.slot
+
+		stages := tracker.Snapshot()
+		stage := failedStage(stages)
+		m.update(job, func() {
+			job.tracker = nil
+			job.Stages = stages
+			job.Artifacts = artifacts(b)
+			if b != nil {
+				job.Bundle = b.Dir
+			}
+
+			switch {
+			case err == nil:
+				finish(job, Succeeded, nil)
+			case ctx.Err() != nil:
+				finish(job, Cancelled, err)
+			default:
+				job.FailedStage = stage
+				delay, retry := m.cfg.Queue.RetryDelay(string(stage), job.Attempts)
+				if !retry {
+					finish(job, Dead, err)
+					return
+				}
+				next := time.Now().Add(delay)
+				job.State = Retrying
+				job.Error = err.Error()
+				job.NextAttemptAt = &next
+			}
+		})
+		release()
+
+		m.mu.Lock()
+		state, message, next := job.State, job.Error, job.NextAttemptAt
+		m.mu.Unlock()
+		switch state {
+		case Retrying:
+			log.Printf("Job %s: failed in %s, retrying at %s: %s", job.ID, stage, next.Format(time.RFC3339), message)
+			continue
+		case Succeeded:
+			log.Printf("Job %s: succeeded", job.ID)
+		default:
+			log.Printf("Job %s: %s: %s", job.ID, state, message)
+		}
+		return
+	}
+}
+
+// execute runs a single attempt, turning a panic into an error
+func (m *Manager) execute(ctx context.Context, id string, req Request, tracker *progress.Tracker) (b *bundle.Bundle, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return Execute(ctx, m.cfg, id, req, tracker)
+}
+
+// update changes a job under the lock, bumps its version and writes it to the journal
+func (m *Manager) update(job *Job, change func()) Job {
+	m.mu.Lock()
+	change()
+	s := m.changedLocked(job)
+	m.mu.Unlock()
+
+	m.save(s)
+	return s
+}
+
+// claim changes a job that is about to run like update, and claims it in the journal so no other process runs it at
+// the same time. If another process claimed the job first, the job takes the state the journal has of it, is followed
+// instead of run and claim returns false. release has to be called after the attempt has been written to the journal.
+func (m *Manager) claim(job *Job, change func()) (release func(), claimed bool) {
+	m.mu.Lock()
+	change()
+	s := m.changedLocked(job)
+	m.mu.Unlock()
+
+	if m.journal == nil {
+		return func() {}, true
+	}
+	release, saved, err := m.journal.Claim(s)
+	if err != nil {
+		log.Printf("Job %s: %v", job.ID, err)
+		return func() {}, true
+	}
+	if release == nil {
+		log.Printf("Job %s: run by another process", job.ID)
+		m.mu.Lock()
+		cancel := job.cancel
+		*job = saved
+		m.mu.Unlock()
+		cancel()
+		return nil, false
+	}
+	return release, true
+}
+
+// changedLocked bumps the version of a changed job and returns a snapshot of it. m.mu has to be held.
+func (m *Manager) changedLocked(job *Job) Job {
+	job.Version++
+	s := snapshot(job)
+	if job.Finished() {
+		m.pruneLocked()
+	}
+	return s
+}
+
+// save writes a snapshot of a job to the journal. Failing to do so does not stop the job.
+func (m *Manager) save(s Job) {
+	if m.journal != nil {
+		if err := m.journal.Append(s); err != nil {
+			log.Printf("Job %s: %v", s.ID, err)
+		}
+	}
+}
+
+// pruneLocked forgets old finished jobs. m.mu has to be held.
+func (m *Manager) pruneLocked() {
+	all := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		all = append(all, *job)
+	}
+	kept := make(map[string]bool)
+	for _, job := range prune(all, m.keep) {
+		kept[job.ID] = true
+	}
+	for id := range m.jobs {
+		if !kept[id] {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// prune returns the jobs without the oldest succeeded and cancelled ones, keeping at most keep of them.
+// Unfinished and dead jobs are always kept. A keep of 0 keeps everything.
+func prune(jobs []Job, keep int) []Job {
+	if keep <= 0 {
+		return jobs
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].CreatedAt.Before(jobs[b].CreatedAt) })
+
+	done := 0
+	for _, job := range jobs {
+		if job.State == Succeeded || job.State == Cancelled {
+			done++
+		}
+	}
+	var kept []Job
+	for _, job := range jobs {
+		if (job.State == Succeeded || job.State == Cancelled) && done > keep {
+			done--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	return kept
+}
+
+// finish records the final state of a job
+func finish(job *Job, state State, err error) {
+	now := time.Now()
+	job.State = state
+	job.FinishedAt = &now
+	job.NextAttemptAt = nil
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// resetForRequeue puts a finished job back in the queue with its attempts reset
+func resetForRequeue(job *Job) {
+	job.State = Queued
+	job.Attempts = 0
+	job.Error = ""
+	job.FailedStage = ""
+	job.NextAttemptAt = nil
+	job.FinishedAt = nil
+}
+
+// failedStage returns the stage that failed, or an empty stage if the job failed outside of a stage
+func failedStage(stages []progress.StageStatus) progress.Stage {
+	for _, s := range stages {
+		if s.Status == progress.Failed {
+			return s.Stage
+		}
+	}
+	return ""
+}
+
+// snapshot copies a job so it can be used without the lock. The lock has to be held.
+func snapshot(job *Job) Job {
+	s := *job
+	if job.tracker != nil {
+		s.Stages = job.tracker.Snapshot()
+	}
+	s.Stages = append([]progress.StageStatus(nil), s.Stages...)
+	s.Artifacts = append([]string(nil), job.Artifacts...)
+	s.tracker = nil
+	s.cancel = nil
+	return s
+}
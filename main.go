@@ -1,66 +1,101 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 
-	createQuoteVideo "videoCreater/createQuoteVideo"
-	createRedditVideo "videoCreater/createRedditVideo"
+	"videoCreater/config"
 
 	"github.com/joho/godotenv"
 )
 
-func init() {
-	initConfig()
-}
+const usage = `Usage: videocreater <command> [flags] [arguments]
+
+Commands:
+  quote            Create a quote video and publish it
+  reddit <sub>     Create a video of the newest post in r/<sub> and publish it
+  render <type>    Create a video of a type and write it to a bundle instead of publishing it
+  publish <bundle> Publish a bundle written by render or --dry-run
+  upload <video>   Publish a single video file
+  serve            Run the jobs in the config's daemon section on their schedules
+  doctor           Check the config and the environment before a run
+  queue <action>   List, show and requeue the jobs in the queue of serve
+  history          List past runs and show what a video was made from
+  resume [run id]  Continue a failed run after the last stage it finished
+
+Run 'videocreater <command> --help' for the flags of a command.
+`
 
 func main() {
 	// Checks if the minimum number of arguments is not met
 	if len(os.Args) < 2 {
-		log.Println("Usage: videocreater <videotype> [extra info]")
+		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
-	videoType := os.Args[1]
 
-	switch videoType {
-	case "quote":
-		createQuoteVideo.CreateQuoteVideo()
-
-	case "reddit":
-		// Ensure that subreddit argument is also provided
-		if len(os.Args) < 3 {
-			log.Println("videotype 'reddit' requires the subreddit name as well")
-			os.Exit(1)
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+			fmt.Print(usage)
+			return
 		}
-		createRedditVideo.CreateRedditVideo(os.Args[2])
+		fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n%s", os.Args[1], usage)
+		os.Exit(1)
+	}
+
+	// Load the env variables from .env, if there is one
+	godotenv.Load()
 
-	default:
-		log.Println("Unknown videotype. Use 'quote' or 'reddit'.")
-		os.Exit(1) // Exit after logging the unknown type error
+	if err := command(os.Args[2:]); err != nil {
+		log.Println(err)
+		os.Exit(1)
 	}
 }
 
-// Initialize environment and verify configuration
-func initConfig() {
-	godotenv.Load()
+// Initialize configuration and the working directories
+func initConfig(configPath string) (*config.Config, error) {
+	// Load the config file, falling back to the built in defaults if there is none
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %v", err)
+	}
 
-	// Check if the API key is set
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	if apiKey == "" {
-		log.Fatalf("GOOGLE_API_KEY environment variable is not set")
+	ensureDirExists(cfg.WorkspaceDir())
+	return cfg, nil
+}
+
+// selectProfile returns the named profile, or the one in VIDEOCREATER_PROFILE or the default profile if name is empty
+func selectProfile(cfg *config.Config, name string) (*config.Profile, error) {
+	if name == "" {
+		name = os.Getenv("VIDEOCREATER_PROFILE")
+	}
+	profile, err := cfg.Profile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select profile: %v", err)
 	}
+	return profile, nil
+}
 
-	// Ensure necessary directories exist
-	ensureDirExists("raw-videos")
-	ensureDirExists("edited-videos")
-	ensureDirExists("text-to-speeched")
-	ensureDirExists("logos")
+// loadConfig loads the config file from path, VIDEOCREATER_CONFIG or config.json
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		path = os.Getenv("VIDEOCREATER_CONFIG")
+	}
+	if path == "" {
+		path = config.DefaultPath
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			log.Printf("No %s found, using the default settings", path)
+			return config.Default(), nil
+		}
+	}
+	return config.Load(path)
 }
 
 // Ensure directory exists
 func ensureDirExists(dir string) {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.Mkdir(dir, 0755); err != nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
 			log.Fatalf("Failed to create directory %s: %v", dir, err)
 		}
 	}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"videoCreater/config"
+	"videoCreater/safety"
+	"videoCreater/voice"
+)
+
+// censorContent masks the swear words and the blocked words of the mask severity of everything shown of the content
+// but the narrated text, which is censored when it is narrated
+func censorContent(profile *config.Profile, content *Content) error {
+	censor, filter, err := censors(profile)
+	if err != nil {
+		return err
+	}
+	mask := func(text string) string { return filter.Mask(censor.Mask(text)) }
+	content.Heading = mask(content.Heading)
+	content.Author = mask(content.Author)
+	content.Title = mask(content.Title)
+	content.Description = mask(content.Description)
+	content.PartTitle = mask(content.PartTitle)
+	for i := range content.Segments {
+		content.Segments[i].Heading = mask(content.Segments[i].Heading)
+	}
+	return nil
+}
+
+// censors returns the censor of the profile, nil when it does not censor, and the filter of its blocklist
+func censors(profile *config.Profile) (*safety.Censor, *safety.Filter, error) {
+	var censor *safety.Censor
+	if profile.Censor.Mode != "" {
+		var err error
+		if censor, err = safety.NewCensor(profile.Censor.Words); err != nil {
+			return nil, nil, fmt.Errorf("censor: %v", err)
+		}
+	}
+	filter, err := safety.New(profile.Safety.Blocklist)
+	if err != nil {
+		return nil, nil, err
+	}
+	return censor, filter, nil
+}
+
+// censorScript masks the swear words and the blocked words of the mask severity where the script shows them. In the
+// bleep mode of the censor it returns the masked words, as the aligned timings of the narration show them, to bleep
+// them. Otherwise the replacement of the censor is said instead.
+func censorScript(profile *config.Profile, script *voice.Script) (map[string]bool, error) {
+	settings := profile.Censor
+	censor, filter, err := censors(profile)
+	if err != nil {
+		return nil, err
+	}
+	// The shown text is masked as a whole, so blocked phrases of several words are found. Masking keeps every word.
+	masked := strings.Fields(filter.Mask(censor.Mask(script.Shown())))
+	bleeped := make(map[string]bool)
+	for i, word := range script.Words {
+		if masked[i] == word.Shown {
+			continue
+		}
+		script.Words[i].Shown = masked[i]
+		if settings.Mode == config.CensorBleep {
+			bleeped[masked[i]] = true
+			continue
+		}
+		said := censor.Replace(word.Said, settings.Spoken())
+		if said == word.Said {
+			// A blocked word, or a swear word said differently than it is shown, is replaced with the punctuation kept
+			isLetter := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+			if start := strings.IndexFunc(said, isLetter); start >= 0 {
+				said = said[:start] + settings.Spoken() + said[strings.LastIndexFunc(said, isLetter)+1:]
+			}
+		}
+		script.Words[i].Said = said
+	}
+	return bleeped, nil
+}
+
+// bleep plays a tone over the bleeped words in the files of the narration
+func bleep(narration *Narration, bleeped map[string]bool) error {
+	if len(bleeped) == 0 {
+		return nil
+	}
+	for i, words := range narration.WordTimings {
+		var hidden []voice.WordInfo
+		for _, word := range words {
+			if bleeped[word.Word] {
+				hidden = append(hidden, word)
+			}
+		}
+		if err := voice.Bleep(narration.Files[i], hidden); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/progress"
+)
+
+// CheckpointFile is the name of the checkpoint record in a checkpoint directory
+const CheckpointFile string = "checkpoint.json"
+
+// Checkpoint holds the outputs of the stages of a run that finished, so a failed run continues where it stopped
+// instead of fetching and narrating everything again. The files of a stage are moved out of the workspace into
+// the checkpoint directory. A nil Checkpoint is valid and records nothing.
+type Checkpoint struct {
+	Dir       string           `json:"-"`
+	RunID     string           `json:"runId"`
+	Request   json.RawMessage  `json:"request"` // What the run was started with, so it can be resumed
+	Stages    []progress.Stage `json:"stages"`  // Stages that finished
+	UpdatedAt time.Time        `json:"updatedAt"`
+
+	Content   *Content       `json:"content,omitempty"`
+	Narration *Narration     `json:"narration,omitempty"`
+	Footage   []Footage      `json:"footage,omitempty"`
+	Videos    []string       `json:"videos,omitempty"` // Rendered videos
+	Bundle    *bundle.Bundle `json:"bundle,omitempty"` // Videos moved to the output dir, and which of them were published
+}
+
+// NewCheckpoint creates an empty checkpoint for a run in dir. Nothing is written until a stage finishes.
+func NewCheckpoint(dir, runID string, request json.RawMessage) *Checkpoint {
+	return &Checkpoint{Dir: dir, RunID: runID, Request: request}
+}
+
+// LoadCheckpoint reads the checkpoint in dir. The error satisfies os.IsNotExist if the run has no checkpoint.
+func LoadCheckpoint(dir string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CheckpointFile))
+	if err != nil {
+		return nil, err
+	}
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	c.Dir = dir
+	return &c, nil
+}
+
+// ListCheckpoints returns the checkpoints of every run under root that can be resumed, oldest first
+func ListCheckpoints(root string) ([]*Checkpoint, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %v", err)
+	}
+
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c, err := LoadCheckpoint(filepath.Join(root, entry.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].UpdatedAt.Before(checkpoints[j].UpdatedAt) })
+	return checkpoints, nil
+}
+
+// Done returns true if the stage finished in an earlier attempt
+func (c *Checkpoint) Done(stage progress.Stage) bool {
+	if c == nil {
+		return false
+	}
+	for _, s := range c.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// Last returns the last stage that finished, or an empty stage if none did
+func (c *Checkpoint) Last() progress.Stage {
+	if c == nil || len(c.Stages) == 0 {
+		return ""
+	}
+	return c.Stages[len(c.Stages)-1]
+}
+
+// content returns the content fetched by an earlier attempt, or nil if the stage has to run
+func (c *Checkpoint) content() *Content {
+	if !c.Done(progress.FetchContent) {
+		return nil
+	}
+	return c.Content
+}
+
+// narration returns the narration of an earlier attempt, or nil if the stage has to run
+func (c *Checkpoint) narration() *Narration {
+	if !c.Done(progress.TTS) || c.Narration == nil || !exists(c.Narration.Files) {
+		return nil
+	}
+	return c.Narration
+}
+
+// footage returns the footage downloaded by an earlier attempt, or nil if the stage has to run
+func (c *Checkpoint) footage() []Footage {
+	if !c.Done(progress.Footage) || len(c.Footage) == 0 {
+		return nil
+	}
+	for _, f := range c.Footage {
+		if !exists([]string{f.Path}) {
+			return nil
+		}
+	}
+	return c.Footage
+}
+
+// videos returns the videos rendered by an earlier attempt, or nil if the stage has to run
+func (c *Checkpoint) videos() []string {
+	if !c.Done(progress.Render) || len(c.Videos) == 0 || !exists(c.Videos) {
+		return nil
+	}
+	return c.Videos
+}
+
+// Keep moves the output files of a stage into the checkpoint and returns their new paths
+func (c *Checkpoint) Keep(stage progress.Stage, paths []string) ([]string, error) {
+	if c == nil {
+		return paths, nil
+	}
+	dir := filepath.Join(c.Dir, string(stage))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %v", err)
+	}
+
+	kept := make([]string, len(paths))
+	used := make(map[string]bool)
+	for i, path := range paths {
+		// Rendered videos are moved on to the output dir, so files keep their names unless two are the same
+		name := filepath.Base(path)
+		if used[name] {
+			name = fmt.Sprintf("%d-%s", i+1, name)
+		}
+		used[name] = true
+
+		kept[i] = filepath.Join(dir, name)
+		if err := bundle.MoveFile(path, kept[i]); err != nil {
+			return nil, fmt.Errorf("failed to move %s into the checkpoint: %v", path, err)
+		}
+	}
+	return kept, nil
+}
+
+// Save records that a stage finished, together with the outputs set on the checkpoint
+func (c *Checkpoint) Save(stage progress.Stage) error {
+	if c == nil {
+		return nil
+	}
+	if !c.Done(stage) {
+		c.Stages = append(c.Stages, stage)
+	}
+	c.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint: %v", err)
+	}
+
+	// Write to a temporary file first, so a crash never leaves half a checkpoint
+	tmp := filepath.Join(c.Dir, CheckpointFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(c.Dir, CheckpointFile)); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint and the files kept in it
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.RemoveAll(c.Dir); err != nil {
+		return fmt.Errorf("failed to remove checkpoint %s: %v", c.Dir, err)
+	}
+	return nil
+}
+
+// exists returns true if every file exists
+func exists(paths []string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"context"
+
+	"videoCreater/getVideo"
+	"videoCreater/workspace"
+)
+
+func init() {
+	RegisterFootage("pexels", pexels{})
+	RegisterFootage("youtube-gameplay", youtubeGameplay{query: "subway surfers gameplay no copyright"})
+}
+
+// pexels fetches a stock video about the content's query for every part of the narration
+type pexels struct{}
+
+func (pexels) Fetch(ctx context.Context, run *Run, content *Content, narration *Narration) ([]Footage, error) {
+	videos, err := getVideo.FetchAndStoreVideosPexels(content.Query, len(narration.Files), run.Workspace.Path(workspace.RawVideos), run.used("pexels"))
+	if err != nil {
+		return nil, err
+	}
+	var footage []Footage
+	for _, video := range videos {
+		footage = append(footage, Footage{Provider: "pexels", ID: video.ID, URL: video.URL, Path: video.Path})
+	}
+	return footage, nil
+}
+
+// youtubeGameplay downloads a single gameplay video from YouTube long enough for every part of the narration
+type youtubeGameplay struct {
+	query string
+}
+
+func (y youtubeGameplay) Fetch(ctx context.Context, run *Run, content *Content, narration *Narration) ([]Footage, error) {
+	parts := len(narration.Files)
+	video, err := getVideo.FetchAndDownloadYoutubeVideo(y.query, (3*parts)+1, (10*parts)+1, run.Workspace.Path(workspace.RawVideos), run.used("youtube"))
+	if err != nil {
+		return nil, err
+	}
+	return []Footage{{Provider: "youtube", ID: video.ID, URL: video.URL, Path: video.Path}}, nil
+}
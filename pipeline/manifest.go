@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/safety"
+)
+
+// ManifestFile is the name of the manifest written into the bundle of a dry run
+const ManifestFile string = "manifest.json"
+
+// Status is how a run ended
+type Status string
+
+const (
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+// Manifest records what a run was made from and what it produced, so a published video can be traced back to its sources
+type Manifest struct {
+	RunID      string          `json:"runId"`
+	Type       string          `json:"type"`
+	Profile    string          `json:"profile"`
+	Input      Input           `json:"input"`
+	DryRun     bool            `json:"dryRun,omitempty"`
+	Status     Status          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Stages     config.Pipeline `json:"stages"`             // Implementations the run was made with
+	Content    interface{}     `json:"content,omitempty"`  // The quote or Reddit post the video was made from
+	Scrubbed   []safety.Scrub  `json:"scrubbed,omitempty"` // Personal information left out of the video
+	Voice      config.Voice    `json:"voice"`
+	Persona    string          `json:"persona,omitempty"`   // Why the voice was picked, empty for the voice of the profile
+	Narrators  []string        `json:"narrators,omitempty"` // Provider and voice the video was narrated with, more than one if the fallback took over
+	Footage    []Footage       `json:"footage,omitempty"`
+	Bundle     string          `json:"bundle,omitempty"` // Directory of the bundle of a dry run
+	Videos     []bundle.Video  `json:"videos,omitempty"` // Output files and where they were published
+}
+
+// Finish records how the run ended
+func (m *Manifest) Finish(err error) {
+	m.FinishedAt = time.Now()
+	m.Status = Succeeded
+	m.Error = ""
+	if err != nil {
+		m.Status = Failed
+		m.Error = err.Error()
+	}
+}
+
+// Write saves the manifest as indented JSON
+func (m *Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// recordVideos copies the output files of the bundle and where they were published into the manifest
+func (m *Manifest) recordVideos(b *bundle.Bundle) {
+	m.Bundle = b.Dir
+	m.Videos = nil
+	for _, video := range b.Videos {
+		v := *video
+		v.File = b.Path(video.File)
+		if v.Captions != "" {
+			v.Captions = b.Path(video.Captions)
+		}
+		m.Videos = append(m.Videos, v)
+	}
+}
+
+// recordContent records what the video was made from and how it was narrated
+func (m *Manifest) recordContent(content *Content) {
+	m.Content, m.Scrubbed = content.Source, content.Scrubbed
+	if content.Voice != nil {
+		m.Voice, m.Persona = *content.Voice, content.Persona
+	}
+}
+
+// recordNarration records which providers and voices narrated the video
+func (m *Manifest) recordNarration(narration *Narration) {
+	m.Narrators = nil
+	for _, v := range narration.Voices {
+		if !slices.Contains(m.Narrators, v) {
+			m.Narrators = append(m.Narrators, v)
+		}
+	}
+}
+
+// recordFootage copies the footage into the manifest, without the paths in the workspace that are gone after the run
+func (m *Manifest) recordFootage(footage []Footage) {
+	m.Footage = make([]Footage, len(footage))
+	for i, f := range footage {
+		f.Path = ""
+		m.Footage[i] = f
+	}
+}
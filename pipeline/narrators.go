@@ -0,0 +1,30 @@
+package pipeline
+
+import (
+	"context"
+
+	"videoCreater/config"
+	"videoCreater/voice"
+	"videoCreater/workspace"
+)
+
+func init() {
+	RegisterNarrator("tts", textToSpeech{})
+	RegisterNarrator("unrealspeech", textToSpeech{}) // The name of tts from before Google could narrate
+}
+
+// textToSpeech narrates with the text to speech provider of the voice, UnrealSpeech or Google, and with its fallback
+// when the provider fails
+type textToSpeech struct{}
+
+func (textToSpeech) Narrate(ctx context.Context, run *Run, text string, settings config.Voice) (*Narration, error) {
+	files, wordTimings, used, err := voice.ConvertTextToSpeech(ctx, text, settings, run.Workspace.Path(workspace.TextToSpeeched))
+	if err != nil {
+		return nil, err
+	}
+	narration := &Narration{Files: files, WordTimings: wordTimings}
+	for range files {
+		narration.Voices = append(narration.Voices, used.ProviderName()+"/"+used.ID)
+	}
+	return narration, nil
+}
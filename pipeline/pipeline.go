@@ -0,0 +1,509 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/progress"
+	"videoCreater/safety"
+	"videoCreater/seen"
+	"videoCreater/voice"
+	"videoCreater/workspace"
+)
+
+// Run is a single run of a pipeline, handed to every stage
+type Run struct {
+	ID         string
+	Input      Input
+	Profile    *config.Profile
+	Workspace  *workspace.Workspace // Where the stages keep their files, removed after the run
+	Manifest   *Manifest            // Filled in as the run goes
+	Checkpoint *Checkpoint          // Outputs of the stages finished by earlier attempts, nil to not checkpoint the run
+	Seen       *seen.Store          // Content the channel used before, nil to not skip anything
+}
+
+// Input is what a run asks of its content source
+type Input struct {
+	Subreddit string `json:"subreddit,omitempty"` // Used by the reddit source
+	Theme     string `json:"theme,omitempty"`     // Used by the favqs source, a random theme of the profile if empty
+}
+
+// Content is what a video is made from
+type Content struct {
+	Kind        string      `json:"kind"`             // Kind and ID identify the content in the content history, like reddit and the post id
+	ID          string      `json:"id"`               // Empty if the content is not recorded
+	Heading     string      `json:"heading"`          // Shown on top of the video
+	Author      string      `json:"author,omitempty"` // Shown after the narration, empty for none
+	Text        string      `json:"text"`             // Narrated
+	Query       string      `json:"query"`            // What the footage should show, used by footage providers that search by theme
+	Title       string      `json:"title"`            // Title of the published video
+	Description string      `json:"description"`      // Description of the published video
+	Tags        []string    `json:"tags,omitempty"`   // Tags of the published video
+	Source      interface{} `json:"source"`           // Written to source.json of a bundle
+
+	// Segments are narrated one after the other instead of Text, each shown under a heading of its own
+	Segments []Segment `json:"segments,omitempty"`
+
+	// Voice is what the content is narrated with, nil for the voice of the profile. Persona tells why it was picked.
+	Voice   *config.Voice `json:"voice,omitempty"`
+	Persona string        `json:"persona,omitempty"`
+
+	// Scrubbed is the personal information replaced by placeholders in the narrated and shown text
+	Scrubbed []safety.Scrub `json:"scrubbed,omitempty"`
+
+	// PartTitle is the title of a part of a video split in several parts, with {part} and {parts} replaced by the
+	// number of the part and the number of parts. If empty " part i of n" is added to Title.
+	PartTitle string `json:"partTitle,omitempty"`
+}
+
+// Segment is a part of the content with its own heading, like a comment narrated after the post
+type Segment struct {
+	Heading string `json:"heading"`
+	Text    string `json:"text"`
+}
+
+// Footage is a background video and where it came from
+type Footage struct {
+	Provider string `json:"provider"` // Site the video was downloaded from, like pexels or youtube
+	ID       string `json:"id"`       // Id of the video on that site
+	URL      string `json:"url"`
+	Path     string `json:"path,omitempty"` // Where the video was saved in the workspace
+}
+
+// Narration is the narrated text, split in parts that each fit in a single request to the narrator
+type Narration struct {
+	Files       []string           `json:"files"`              // Audio file of every part
+	WordTimings [][]voice.WordInfo `json:"wordTimings"`        // When every word of a part is spoken
+	Headings    []string           `json:"headings,omitempty"` // Heading of every part of narrated segments, empty to show Content.Heading
+	Voices      []string           `json:"voices,omitempty"`   // Provider and voice that narrated every part, like unrealspeech/Liv
+}
+
+// voice returns the voice the content is narrated with
+func (c *Content) voice(profile *config.Profile) config.Voice {
+	if c.Voice != nil {
+		return *c.Voice
+	}
+	return profile.Voice
+}
+
+// Heading returns the heading shown during a part of the narration
+func (n *Narration) Heading(part int, content *Content) string {
+	if part < len(n.Headings) {
+		return n.Headings[part]
+	}
+	return content.Heading
+}
+
+// ContentSource fetches the content of a video
+type ContentSource interface {
+	// Check returns an error if the input can not be used by the source
+	Check(in Input) error
+	Fetch(ctx context.Context, run *Run) (*Content, error)
+}
+
+// Narrator turns text into speech, writing the audio to the workspace of the run
+type Narrator interface {
+	Narrate(ctx context.Context, run *Run, text string, settings config.Voice) (*Narration, error)
+}
+
+// usedRecorder is implemented by content sources that keep a record of their own of the content made into videos
+type usedRecorder interface {
+	RecordUsed(run *Run, content *Content) error
+}
+
+// FootageProvider fetches the background videos the narration is rendered on top of into the workspace of the run
+type FootageProvider interface {
+	Fetch(ctx context.Context, run *Run, content *Content, narration *Narration) ([]Footage, error)
+}
+
+// Renderer edits the footage and narration into finished videos in the workspace of the run, returning one video per part
+type Renderer interface {
+	Render(ctx context.Context, run *Run, footage []Footage, narration *Narration, content *Content) ([]string, error)
+}
+
+// Publisher uploads videos to a platform
+type Publisher interface {
+	// Options returns whether the profile posts to the platform and whether videos are deleted after posting them
+	Options(profile *config.Profile) (post, deleteAfterPost bool)
+	// Describe fills in the platform specific metadata of a video
+	Describe(video *bundle.Video, profile *config.Profile)
+	// Publish uploads a video and returns its id on the platform
+	Publish(ctx context.Context, path string, video *bundle.Video) (string, error)
+}
+
+// Pipeline is a video type: one implementation of every stage
+type Pipeline struct {
+	Name      string
+	Source    ContentSource
+	Narrator  Narrator
+	Footage   FootageProvider
+	Renderer  Renderer
+	Publisher Publisher
+	Platform  string          // Name of the publisher, recorded in bundles so they can be published later
+	Stages    config.Pipeline // Names of the implementations of every stage
+}
+
+// New builds the pipeline of a video type from the implementations registered under the names in the config
+func New(cfg *config.Config, name string) (*Pipeline, error) {
+	composition, err := cfg.Pipeline(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline{Name: name, Platform: composition.Publisher, Stages: *composition}
+	if p.Source, err = sources.get(composition.Source); err != nil {
+		return nil, fmt.Errorf("video type %q: %v", name, err)
+	}
+	if p.Narrator, err = narrators.get(composition.Narrator); err != nil {
+		return nil, fmt.Errorf("video type %q: %v", name, err)
+	}
+	if p.Footage, err = footage.get(composition.Footage); err != nil {
+		return nil, fmt.Errorf("video type %q: %v", name, err)
+	}
+	if p.Renderer, err = renderers.get(composition.Renderer); err != nil {
+		return nil, fmt.Errorf("video type %q: %v", name, err)
+	}
+	if p.Publisher, err = publishers.get(composition.Publisher); err != nil {
+		return nil, fmt.Errorf("video type %q: %v", name, err)
+	}
+	return p, nil
+}
+
+// Run makes a video and publishes it. With dryRun set the video is written to a bundle instead, to be published later.
+// Otherwise the video is moved to the output dir of the profile. The progress of every stage is recorded in tracker,
+// which may be nil. The run stops between stages if ctx is cancelled.
+func (p *Pipeline) Run(ctx context.Context, run *Run, dryRun bool, tracker *progress.Tracker) (*bundle.Bundle, error) {
+	profile := run.Profile
+	if run.Manifest == nil {
+		run.Manifest = &Manifest{}
+	}
+	run.Manifest.Stages = p.Stages
+	run.Manifest.Voice = profile.Voice
+
+	b, content, err := p.resumeBundle(run, dryRun, tracker)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		var videos []string
+		var narration *Narration
+		content, videos, narration, err = p.createVideo(ctx, run, tracker)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create video: %v", err)
+		}
+		if !dryRun {
+			// The workspace is removed after the run, so the videos are kept in the output dir
+			if videos, err = moveToOutputDir(videos, profile.OutputDir); err != nil {
+				return nil, fmt.Errorf("failed to move video to %s: %v", profile.OutputDir, err)
+			}
+		}
+		b = p.newBundle(content, videos, narration, profile)
+		p.recordUsed(run, content)
+
+		if !dryRun {
+			// The rendered videos have left the checkpoint, a retry publishes them from the output dir
+			if run.Checkpoint != nil {
+				run.Checkpoint.Bundle = b
+				checkpoint(run, progress.Render)
+			}
+		}
+	}
+	defer run.Manifest.recordVideos(b)
+
+	if dryRun {
+		tracker.Skip(progress.Upload)
+		if err := b.Write(profile.OutputDir, content.Source); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %v", err)
+		}
+		log.Printf("Wrote bundle %s", b.Dir)
+		return b, nil
+	}
+
+	if err := tracker.Start(ctx, progress.Upload); err != nil {
+		return b, err
+	}
+	err = Publish(ctx, b, profile, false)
+	tracker.Finish(progress.Upload, err)
+	if err != nil {
+		// Record which videos were published, so a retry does not publish them twice
+		checkpoint(run, progress.Render)
+		return b, fmt.Errorf("failed to upload video: %v", err)
+	}
+	return b, nil
+}
+
+// recordUsed adds the content and footage of a made video to the content history of the channel
+func (p *Pipeline) recordUsed(run *Run, content *Content) {
+	var entries []seen.Entry
+	if content.ID != "" {
+		entries = append(entries, seen.Entry{Channel: run.Profile.Name, Kind: content.Kind, Key: content.ID, RunID: run.ID})
+	}
+	for _, f := range run.Manifest.Footage {
+		entries = append(entries, seen.Entry{Channel: run.Profile.Name, Kind: f.Provider, Key: f.ID, RunID: run.ID})
+	}
+	if err := run.Seen.Record(entries...); err != nil {
+		log.Printf("Failed to record the content of run %s: %v", run.ID, err)
+	}
+	if recorder, ok := p.Source.(usedRecorder); ok && content.ID != "" {
+		if err := recorder.RecordUsed(run, content); err != nil {
+			log.Printf("Failed to record the content of run %s: %v", run.ID, err)
+		}
+	}
+}
+
+// used returns whether the channel of the run used content of a kind before
+func (r *Run) used(kind string) func(id string) bool {
+	return func(id string) bool {
+		return r.Seen.Used(r.Profile.Name, kind, id)
+	}
+}
+
+// newBundle describes the rendered videos for the publisher
+func (p *Pipeline) newBundle(content *Content, videos []string, narration *Narration, profile *config.Profile) *bundle.Bundle {
+	b := &bundle.Bundle{
+		Type:      p.Name,
+		Profile:   profile.Name,
+		CreatedAt: time.Now(),
+	}
+	for i, file := range videos {
+		title := content.Title
+		if len(videos) > 1 {
+			if content.PartTitle != "" {
+				title = strings.NewReplacer("{part}", strconv.Itoa(i+1), "{parts}", strconv.Itoa(len(videos))).Replace(content.PartTitle)
+			} else {
+				title = fmt.Sprintf("%s part %d of %d", content.Title, i+1, len(videos))
+			}
+		}
+
+		video := &bundle.Video{
+			File:        file,
+			Platform:    p.Platform,
+			Title:       title,
+			Description: content.Description,
+			Tags:        content.Tags,
+		}
+		if i < len(narration.WordTimings) {
+			video.WordTimings = narration.WordTimings[i]
+		}
+		p.Publisher.Describe(video, profile)
+		b.Videos = append(b.Videos, video)
+	}
+	return b
+}
+
+// resumeBundle returns the bundle of an earlier attempt that got as far as moving its videos to the output dir,
+// or nil if the run has to make the videos
+func (p *Pipeline) resumeBundle(run *Run, dryRun bool, tracker *progress.Tracker) (*bundle.Bundle, *Content, error) {
+	cp := run.Checkpoint
+	if dryRun || cp == nil || cp.Bundle == nil || cp.Content == nil {
+		return nil, nil, nil
+	}
+	for _, video := range cp.Bundle.Videos {
+		if video.PublishedID == "" && !exists([]string{video.File}) {
+			return nil, nil, fmt.Errorf("failed to resume run %s: %s is gone from the output dir", run.ID, video.File)
+		}
+	}
+
+	for _, stage := range []progress.Stage{progress.FetchContent, progress.TTS, progress.Footage, progress.Render} {
+		tracker.Resume(stage)
+	}
+	run.Manifest.recordContent(cp.Content)
+	if cp.Narration != nil {
+		run.Manifest.recordNarration(cp.Narration)
+	}
+	run.Manifest.recordFootage(cp.Footage)
+	return cp.Bundle, cp.Content, nil
+}
+
+// createVideo runs every stage up to rendering and returns the content, the rendered videos and the narration.
+// Stages finished by an earlier attempt of the run are taken from its checkpoint instead of being run again.
+func (p *Pipeline) createVideo(ctx context.Context, run *Run, tracker *progress.Tracker) (*Content, []string, *Narration, error) {
+	cp := run.Checkpoint
+
+	// Fetch content
+	content := cp.content()
+	if content != nil {
+		tracker.Resume(progress.FetchContent)
+	} else {
+		if err := tracker.Start(ctx, progress.FetchContent); err != nil {
+			return nil, nil, nil, err
+		}
+		var err error
+		content, err = p.Source.Fetch(ctx, run)
+		if err == nil {
+			err = censorContent(run.Profile, content)
+		}
+		tracker.Finish(progress.FetchContent, err)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to fetch content: %v", err)
+		}
+		if cp != nil {
+			cp.Content = content
+			checkpoint(run, progress.FetchContent)
+		}
+	}
+	run.Manifest.recordContent(content)
+
+	// Convert text to speech
+	narration := cp.narration()
+	if narration != nil {
+		tracker.Resume(progress.TTS)
+	} else {
+		if err := tracker.Start(ctx, progress.TTS); err != nil {
+			return nil, nil, nil, err
+		}
+		var err error
+		narration, err = p.narrate(ctx, run, content)
+		if err == nil && cp != nil {
+			if narration.Files, err = cp.Keep(progress.TTS, narration.Files); err == nil {
+				cp.Narration = narration
+				checkpoint(run, progress.TTS)
+			}
+		}
+		tracker.Finish(progress.TTS, err)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to convert text to speech: %v", err)
+		}
+	}
+	run.Manifest.recordNarration(narration)
+
+	// Fetch video
+	footage := cp.footage()
+	if footage != nil {
+		tracker.Resume(progress.Footage)
+	} else {
+		if err := tracker.Start(ctx, progress.Footage); err != nil {
+			return nil, nil, nil, err
+		}
+		var err error
+		footage, err = p.Footage.Fetch(ctx, run, content, narration)
+		if err == nil && cp != nil {
+			if err = keepFootage(cp, footage); err == nil {
+				cp.Footage = footage
+				checkpoint(run, progress.Footage)
+			}
+		}
+		tracker.Finish(progress.Footage, err)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to fetch video: %v", err)
+		}
+	}
+	run.Manifest.recordFootage(footage)
+
+	// Edit video
+	videos := cp.videos()
+	if videos != nil {
+		tracker.Resume(progress.Render)
+	} else {
+		if err := tracker.Start(ctx, progress.Render); err != nil {
+			return nil, nil, nil, err
+		}
+		var err error
+		videos, err = p.Renderer.Render(ctx, run, footage, narration, content)
+		if err == nil && cp != nil {
+			if videos, err = cp.Keep(progress.Render, videos); err == nil {
+				cp.Videos = videos
+				checkpoint(run, progress.Render)
+			}
+		}
+		tracker.Finish(progress.Render, err)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to edit video: %v", err)
+		}
+	}
+
+	return content, videos, narration, nil
+}
+
+// narrate narrates the text of the content, or every segment on its own so each part has a single heading
+func (p *Pipeline) narrate(ctx context.Context, run *Run, content *Content) (*Narration, error) {
+	if len(content.Segments) == 0 {
+		return p.narrateText(ctx, run, content.Text, content.voice(run.Profile))
+	}
+
+	narration := &Narration{}
+	for i, segment := range content.Segments {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		part, err := p.narrateText(ctx, run, segment.Text, content.voice(run.Profile))
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %v", i+1, err)
+		}
+		narration.Files = append(narration.Files, part.Files...)
+		narration.WordTimings = append(narration.WordTimings, part.WordTimings...)
+		narration.Voices = append(narration.Voices, part.Voices...)
+		for range part.Files {
+			narration.Headings = append(narration.Headings, segment.Heading)
+		}
+	}
+	return narration, nil
+}
+
+// checkpoint saves the checkpoint of the run after a stage. Failing to do so only means the stage is run again on a retry.
+func checkpoint(run *Run, stage progress.Stage) {
+	if err := run.Checkpoint.Save(stage); err != nil {
+		log.Printf("Failed to checkpoint run %s: %v", run.ID, err)
+	}
+}
+
+// keepFootage moves the downloaded footage into the checkpoint
+func keepFootage(cp *Checkpoint, footage []Footage) error {
+	paths := make([]string, len(footage))
+	for i, f := range footage {
+		paths[i] = f.Path
+	}
+	kept, err := cp.Keep(progress.Footage, paths)
+	if err != nil {
+		return err
+	}
+	for i := range footage {
+		footage[i].Path = kept[i]
+	}
+	return nil
+}
+
+// moveToOutputDir moves videos into dir and returns their new paths. Names taken by other runs get a number added.
+func moveToOutputDir(videos []string, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for _, video := range videos {
+		ext := filepath.Ext(video)
+		stem := strings.TrimSuffix(filepath.Base(video), ext)
+		for i := 1; ; i++ {
+			path := filepath.Join(dir, stem+ext)
+			if i > 1 {
+				path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+			}
+
+			// Claim the name first, so a run finishing at the same time can not take it
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if os.IsExist(err) {
+				continue
+			}
+			if err != nil {
+				return moved, err
+			}
+			f.Close()
+
+			if err := bundle.MoveFile(video, path); err != nil {
+				os.Remove(path)
+				return moved, err
+			}
+			moved = append(moved, path)
+			break
+		}
+	}
+	return moved, nil
+}
@@ -0,0 +1,267 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/progress"
+	"videoCreater/seen"
+	"videoCreater/workspace"
+)
+
+// fakes is a pipeline of fake stages that record what they are called with
+type fakes struct {
+	mu          sync.Mutex
+	fetched     int
+	narrated    []string
+	rendered    [][]Footage
+	published   []string
+	failNarrate bool
+}
+
+type fakeSource struct{ *fakes }
+
+func (fakeSource) Check(in Input) error { return nil }
+
+func (f fakeSource) Fetch(ctx context.Context, run *Run) (*Content, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched++
+	content := &Content{Kind: "fake", ID: "post-1", Heading: "Heading", Text: "Hello world.", Title: "Title", Source: "source"}
+	if run.Input.Theme == "segments" {
+		content.Segments = []Segment{{Heading: "First", Text: "One."}, {Heading: "Second", Text: "Two."}}
+	}
+	return content, nil
+}
+
+type fakeNarrator struct{ *fakes }
+
+func (f fakeNarrator) Narrate(ctx context.Context, run *Run, text string, settings config.Voice) (*Narration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failNarrate {
+		return nil, fmt.Errorf("narrator is down")
+	}
+	f.narrated = append(f.narrated, text)
+	path := run.Workspace.Path(workspace.TextToSpeeched, fmt.Sprintf("voice%d.mp3", len(f.narrated)))
+	return &Narration{Files: []string{path}, Voices: []string{"fake/voice"}}, os.WriteFile(path, []byte(text), 0644)
+}
+
+type fakeFootage struct{ *fakes }
+
+func (fakeFootage) Fetch(ctx context.Context, run *Run, content *Content, narration *Narration) ([]Footage, error) {
+	path := run.Workspace.Path(workspace.RawVideos, "footage.mp4")
+	return []Footage{{Provider: "fake-footage", ID: "clip-1", Path: path}}, os.WriteFile(path, []byte("footage"), 0644)
+}
+
+type fakeRenderer struct{ *fakes }
+
+func (f fakeRenderer) Render(ctx context.Context, run *Run, footage []Footage, narration *Narration, content *Content) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rendered = append(f.rendered, footage)
+	var videos []string
+	for i := range narration.Files {
+		path := run.Workspace.Path(workspace.Rendered, fmt.Sprintf("video%d.mp4", i+1))
+		if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+			return nil, err
+		}
+		videos = append(videos, path)
+	}
+	return videos, nil
+}
+
+type fakePublisher struct{ *fakes }
+
+func (fakePublisher) Options(profile *config.Profile) (bool, bool) { return true, false }
+
+func (fakePublisher) Describe(video *bundle.Video, profile *config.Profile) {
+	video.Privacy = "private"
+}
+
+func (f fakePublisher) Publish(ctx context.Context, path string, video *bundle.Video) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.published = append(f.published, path)
+	return fmt.Sprintf("id-%d", len(f.published)), nil
+}
+
+// newFakePipeline registers fake stages under the name of the test and returns a config with a video type made of them
+func newFakePipeline(t *testing.T) (*fakes, *config.Config) {
+	f := &fakes{}
+	name := t.Name()
+	RegisterSource(name, fakeSource{f})
+	RegisterNarrator(name, fakeNarrator{f})
+	RegisterFootage(name, fakeFootage{f})
+	RegisterRenderer(name, fakeRenderer{f})
+	RegisterPublisher(name, fakePublisher{f})
+
+	cfg := config.Default()
+	cfg.Pipelines = map[string]*config.Pipeline{
+		"fake": {Source: name, Narrator: name, Footage: name, Renderer: name, Publisher: name},
+	}
+	return f, cfg
+}
+
+// newRun returns a run of the default profile with its workspace, output dir and content history in a temporary directory
+func newRun(t *testing.T, cfg *config.Config, id string, in Input) *Run {
+	dir := t.TempDir()
+	profile := cfg.Profiles["default"].Clone()
+	profile.OutputDir = filepath.Join(dir, "output")
+	ws, err := workspace.New(filepath.Join(dir, "workspaces"), id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	used, err := seen.Open(filepath.Join(dir, "content-history"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Run{ID: id, Input: in, Profile: profile, Workspace: ws, Manifest: &Manifest{RunID: id}, Seen: used}
+}
+
+// statuses returns the status of every stage the tracker recorded
+func statuses(tracker *progress.Tracker) map[progress.Stage]progress.Status {
+	got := make(map[progress.Stage]progress.Status)
+	for _, s := range tracker.Snapshot() {
+		got[s.Stage] = s.Status
+	}
+	return got
+}
+
+func TestNewUnknownStage(t *testing.T) {
+	_, cfg := newFakePipeline(t)
+	cfg.Pipelines["fake"].Renderer = "missing"
+	_, err := New(cfg, "fake")
+	if err == nil || !strings.Contains(err.Error(), `unknown renderer "missing"`) {
+		t.Errorf("got error %v, want one about the unknown renderer", err)
+	}
+	if _, err := New(cfg, "missing"); err == nil {
+		t.Error("got no error for an unknown video type")
+	}
+}
+
+func TestRunPublishes(t *testing.T) {
+	f, cfg := newFakePipeline(t)
+	p, err := New(cfg, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := newRun(t, cfg, "run-1", Input{})
+	tracker := progress.NewTracker()
+
+	b, err := p.Run(context.Background(), run, false, tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.fetched != 1 || len(f.narrated) != 1 || f.narrated[0] != "Hello world." {
+		t.Errorf("fetched %d times and narrated %q, want 1 time and Hello world.", f.fetched, f.narrated)
+	}
+	if len(f.rendered) != 1 || len(f.rendered[0]) != 1 || f.rendered[0][0].ID != "clip-1" {
+		t.Errorf("rendered with footage %+v, want clip-1", f.rendered)
+	}
+	if len(b.Videos) != 1 || len(f.published) != 1 {
+		t.Fatalf("got %d videos and %d published, want 1 of each", len(b.Videos), len(f.published))
+	}
+	video := b.Videos[0]
+	if filepath.Dir(f.published[0]) != run.Profile.OutputDir {
+		t.Errorf("published %s, want a video in %s", f.published[0], run.Profile.OutputDir)
+	}
+	if video.PublishedID != "id-1" || video.Title != "Title" || video.Privacy != "private" || video.Platform != cfg.Pipelines["fake"].Publisher {
+		t.Errorf("got video %+v", video)
+	}
+
+	for _, stage := range progress.Stages {
+		if got := statuses(tracker)[stage]; got != progress.Done {
+			t.Errorf("stage %s is %s, want %s", stage, got, progress.Done)
+		}
+	}
+	if run.Manifest.Stages != *cfg.Pipelines["fake"] || len(run.Manifest.Narrators) != 1 || len(run.Manifest.Footage) != 1 {
+		t.Errorf("got manifest %+v", run.Manifest)
+	}
+	if !run.Seen.Used("default", "fake", "post-1") || !run.Seen.Used("default", "fake-footage", "clip-1") {
+		t.Error("the content and footage are not in the content history")
+	}
+}
+
+func TestRunDryRunWithSegments(t *testing.T) {
+	f, cfg := newFakePipeline(t)
+	p, err := New(cfg, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := newRun(t, cfg, "run-1", Input{Theme: "segments"})
+	tracker := progress.NewTracker()
+
+	b, err := p.Run(context.Background(), run, true, tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(f.narrated, " ") != "One. Two." {
+		t.Errorf("narrated %q, want every segment on its own", f.narrated)
+	}
+	if len(f.published) != 0 {
+		t.Errorf("published %v in a dry run", f.published)
+	}
+	if got := statuses(tracker)[progress.Upload]; got != progress.Skipped {
+		t.Errorf("upload is %s, want %s", got, progress.Skipped)
+	}
+	if b.Dir == "" || filepath.Dir(b.Dir) != run.Profile.OutputDir {
+		t.Fatalf("bundle is in %q, want a directory in %s", b.Dir, run.Profile.OutputDir)
+	}
+	if len(b.Videos) != 2 || b.Videos[0].Title != "Title part 1 of 2" {
+		t.Fatalf("got videos %+v, want two parts", b.Videos)
+	}
+	for _, video := range b.Videos {
+		if _, err := os.Stat(b.Path(video.File)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := bundle.Load(b.Dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	f, cfg := newFakePipeline(t)
+	p, err := New(cfg, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := newRun(t, cfg, "run-1", Input{})
+	run.Checkpoint = NewCheckpoint(filepath.Join(t.TempDir(), "run-1"), "run-1", nil)
+
+	f.failNarrate = true
+	tracker := progress.NewTracker()
+	if _, err := p.Run(context.Background(), run, false, tracker); err == nil || !strings.Contains(err.Error(), "narrator is down") {
+		t.Fatalf("got error %v, want the narrator's", err)
+	}
+	if got := statuses(tracker); got[progress.FetchContent] != progress.Done || got[progress.TTS] != progress.Failed || got[progress.Render] != progress.Pending {
+		t.Errorf("got stages %v", got)
+	}
+	if len(f.rendered) != 0 {
+		t.Error("rendered after the narrator failed")
+	}
+
+	// A second attempt with the same checkpoint does not fetch the content again
+	f.failNarrate = false
+	tracker = progress.NewTracker()
+	if _, err := p.Run(context.Background(), run, false, tracker); err != nil {
+		t.Fatal(err)
+	}
+	if f.fetched != 1 || len(f.narrated) != 1 || len(f.published) != 1 {
+		t.Errorf("fetched %d times, narrated %d times and published %d times, want 1 each", f.fetched, len(f.narrated), len(f.published))
+	}
+	if got := statuses(tracker)[progress.FetchContent]; got != progress.Resumed {
+		t.Errorf("fetch-content is %s, want %s", got, progress.Resumed)
+	}
+}
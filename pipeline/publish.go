@@ -0,0 +1,67 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+)
+
+// Publish uploads every video of the bundle that has not been published yet, using the publisher named by the video's platform.
+// Videos are deleted after posting if the profile asks for it. With force set videos are uploaded even if publishing
+// is turned off for the platform in the profile.
+func Publish(ctx context.Context, b *bundle.Bundle, profile *config.Profile, force bool) error {
+	var errs []string
+	for _, video := range b.Videos {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err.Error())
+			break
+		}
+		if video.PublishedID != "" || video.PublishedAt != nil {
+			log.Printf("Skipping %s, it was already published", video.File)
+			continue
+		}
+
+		publisher, err := publishers.get(video.Platform)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", video.File, err))
+			continue
+		}
+		post, deleteAfterPost := publisher.Options(profile)
+
+		path := b.Path(video.File)
+		if post || force {
+			id, err := publisher.Publish(ctx, path, video)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", video.File, err))
+				continue
+			}
+			now := time.Now()
+			video.PublishedID = id
+			video.PublishedAt = &now
+		}
+
+		if deleteAfterPost {
+			if err := os.Remove(path); err != nil {
+				log.Printf("Error deleting video file: %v", err)
+			}
+		}
+	}
+
+	// Record what was published so the bundle is not published twice
+	if b.Dir != "" {
+		if err := b.Save(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"context"
+
+	"videoCreater/bundle"
+	"videoCreater/config"
+	"videoCreater/upload"
+)
+
+func init() {
+	RegisterPublisher("youtube", youtubePublisher{})
+	RegisterPublisher("tiktok", tiktokPublisher{})
+}
+
+// youtubePublisher uploads to the YouTube channel of the profile
+type youtubePublisher struct{}
+
+func (youtubePublisher) Options(profile *config.Profile) (bool, bool) {
+	return profile.Youtube.Post, profile.Youtube.DeleteAfterPost
+}
+
+func (youtubePublisher) Describe(video *bundle.Video, profile *config.Profile) {
+	video.CategoryID = "22"
+	video.Privacy = profile.Youtube.Privacy
+	video.MadeForKids = profile.Youtube.MadeForKids
+}
+
+func (youtubePublisher) Publish(ctx context.Context, path string, video *bundle.Video) (string, error) {
+	return upload.UploadVideoYoutube(ctx, path, video.Description, video.Title, video.CategoryID, video.Tags, video.Privacy, video.MadeForKids)
+}
+
+// tiktokPublisher uploads to the TikTok account of TIKTOK_CLIENT_KEY
+type tiktokPublisher struct{}
+
+func (tiktokPublisher) Options(profile *config.Profile) (bool, bool) {
+	return profile.TikTok.Post, profile.TikTok.DeleteAfterPost
+}
+
+func (tiktokPublisher) Describe(video *bundle.Video, profile *config.Profile) {
+	video.Privacy = "public"
+}
+
+func (tiktokPublisher) Publish(ctx context.Context, path string, video *bundle.Video) (string, error) {
+	// TikTok does not hand back an id, so the title is recorded instead
+	return video.Title, upload.UploadVideoTikTok(ctx, path, video.Title, video.Description)
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registry holds the implementations of a stage by name
+type registry[T any] struct {
+	kind string
+
+	mu    sync.RWMutex
+	items map[string]T
+}
+
+var (
+	sources    = &registry[ContentSource]{kind: "content source", items: map[string]ContentSource{}}
+	narrators  = &registry[Narrator]{kind: "narrator", items: map[string]Narrator{}}
+	footage    = &registry[FootageProvider]{kind: "footage provider", items: map[string]FootageProvider{}}
+	renderers  = &registry[Renderer]{kind: "renderer", items: map[string]Renderer{}}
+	publishers = &registry[Publisher]{kind: "publisher", items: map[string]Publisher{}}
+)
+
+// RegisterSource makes a content source available to pipelines under name, replacing any source with that name
+func RegisterSource(name string, source ContentSource) { sources.add(name, source) }
+
+// RegisterNarrator makes a narrator available to pipelines under name, replacing any narrator with that name
+func RegisterNarrator(name string, narrator Narrator) { narrators.add(name, narrator) }
+
+// RegisterFootage makes a footage provider available to pipelines under name, replacing any provider with that name
+func RegisterFootage(name string, provider FootageProvider) { footage.add(name, provider) }
+
+// RegisterRenderer makes a renderer available to pipelines under name, replacing any renderer with that name
+func RegisterRenderer(name string, renderer Renderer) { renderers.add(name, renderer) }
+
+// RegisterPublisher makes a publisher available to pipelines and bundles under name, replacing any publisher with that name
+func RegisterPublisher(name string, publisher Publisher) { publishers.add(name, publisher) }
+
+func (r *registry[T]) add(name string, item T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[name] = item
+}
+
+func (r *registry[T]) get(name string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.items[name]
+	if !ok {
+		names := make([]string, 0, len(r.items))
+		for n := range r.items {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return item, fmt.Errorf("unknown %s %q (have: %s)", r.kind, name, strings.Join(names, ", "))
+	}
+	return item, nil
+}
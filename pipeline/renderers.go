@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"videoCreater/editVideo"
+	"videoCreater/workspace"
+)
+
+func init() {
+	RegisterRenderer("youtube", youtubeRenderer{})
+	RegisterRenderer("tiktok", tiktokRenderer{})
+}
+
+// youtubeRenderer renders every part of the narration on its own footage, with the heading and author burned in
+type youtubeRenderer struct{}
+
+func (youtubeRenderer) Render(ctx context.Context, run *Run, footage []Footage, narration *Narration, content *Content) ([]string, error) {
+	if len(footage) == 0 {
+		return nil, fmt.Errorf("no footage to render on")
+	}
+
+	var videos []string
+	for i, audio := range narration.Files {
+		video, err := editVideo.EditVideoYoutube(ctx, footage[i%len(footage)].Path, audio, narration.WordTimings[i], narration.Heading(i, content), content.Author, run.Profile, run.Workspace.Path(workspace.Rendered))
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, nil
+}
+
+// tiktokRenderer renders the parts of the narration one after the other on the first footage
+type tiktokRenderer struct{}
+
+func (tiktokRenderer) Render(ctx context.Context, run *Run, footage []Footage, narration *Narration, content *Content) ([]string, error) {
+	if len(footage) == 0 {
+		return nil, fmt.Errorf("no footage to render on")
+	}
+	headings := make([]string, len(narration.Files))
+	for i := range headings {
+		headings[i] = narration.Heading(i, content)
+	}
+	return editVideo.EditVideoTikTok(ctx, footage[0].Path, narration.Files, narration.WordTimings, headings, run.Profile, run.Workspace.Path(workspace.Rendered))
+}
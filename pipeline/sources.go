@@ -0,0 +1,295 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"videoCreater/createQuoteVideo/quote"
+	"videoCreater/createRedditVideo/reddit"
+	"videoCreater/safety"
+	"videoCreater/seen"
+	"videoCreater/speech"
+)
+
+func init() {
+	RegisterSource("favqs", quoteSource{})
+	RegisterSource("reddit", redditSource{})
+}
+
+// maxClaims is how many times a source picks content again when another run took what it picked
+const maxClaims = 5
+
+// Quote is the content a quote video is made from
+type Quote struct {
+	Thema  string `json:"thema"`
+	Body   string `json:"body"`
+	Author string `json:"author"`
+}
+
+// quoteSource fetches a random quote about a theme from FavQs
+type quoteSource struct{}
+
+func (quoteSource) Check(in Input) error {
+	if in.Subreddit != "" {
+		return fmt.Errorf("subreddit is only used by reddit videos")
+	}
+	return nil
+}
+
+func (quoteSource) Fetch(ctx context.Context, run *Run) (*Content, error) {
+	profile := run.Profile
+	thema := run.Input.Theme
+	if thema == "" {
+		thema = random(profile.Themes)
+	}
+
+	// The quote is claimed as soon as it is picked, so a run picking quotes at the same time picks another one
+	used := run.used("favqs")
+	var body, author string
+	for attempt := 1; ; attempt++ {
+		var err error
+		body, author, err = quote.FetchQuote(thema, func(body string) bool { return used(seen.QuoteKey(body)) })
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch quote: %v", err)
+		}
+		claimed, err := run.Seen.Claim(seen.Entry{Channel: profile.Name, Kind: "favqs", Key: seen.QuoteKey(body), RunID: run.ID})
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			break
+		}
+		if attempt == maxClaims {
+			return nil, fmt.Errorf("failed to fetch quote: every quote picked was taken by another run")
+		}
+		log.Printf("Quote was taken by another run, picking another")
+	}
+
+	title := fmt.Sprintf("A Quote of %s", strings.Title(thema))
+	return &Content{
+		Kind:        "favqs",
+		ID:          seen.QuoteKey(body),
+		Heading:     title,
+		Author:      author,
+		Text:        body,
+		Query:       thema,
+		Title:       title,
+		Description: fmt.Sprintf("A beautiful quote about %s. Leave a Like and Subscribe for more beautiful quotes ", strings.Title(thema)),
+		Tags:        profile.Themes,
+		Source:      &Quote{Thema: thema, Body: body, Author: author},
+	}, nil
+}
+
+// redditSource fetches the best post of a subreddit that was not made into a video yet
+type redditSource struct{}
+
+func (redditSource) Check(in Input) error {
+	if in.Subreddit == "" {
+		return fmt.Errorf("subreddit must be set for reddit videos")
+	}
+	if in.Theme != "" {
+		return fmt.Errorf("theme is only used by quote videos")
+	}
+	return nil
+}
+
+func (redditSource) Fetch(ctx context.Context, run *Run) (*Content, error) {
+	processed, err := openProcessed(run)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := safety.New(run.Profile.Safety.Blocklist)
+	if err != nil {
+		return nil, err
+	}
+	settings := run.Profile.Reddit
+	minDuration, maxDuration := settings.Durations()
+	criteria := &reddit.Criteria{
+		Listing:        settings.Listing,
+		Time:           settings.Time,
+		MinScore:       settings.MinScore,
+		MinUpvoteRatio: settings.MinUpvoteRatio,
+		MinComments:    settings.MinComments,
+		MinDuration:    minDuration,
+		MaxDuration:    maxDuration,
+		AllowNSFW:      run.Profile.Safety.AllowNSFW,
+		AllowSpoilers:  run.Profile.Safety.AllowSpoilers,
+		Blocked:        filter.Blocked,
+		Verdict:        settings.Verdict,
+	}
+	if settings.Comments > 0 {
+		criteria.Comments = &reddit.CommentCriteria{Count: settings.Comments, MinLength: settings.MinCommentLength, MaxLength: settings.MaxCommentLength}
+	}
+	post, err := reddit.GetRedditPost(ctx, run.Input.Subreddit, criteria, processed, run.used("reddit"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reddit post: %v", err)
+	}
+
+	// Markdown is not narrated or shown. Words that do not reject the post are masked where they are shown, and kept out
+	// of the narration when it is narrated.
+	post.Title, post.Content = speech.Clean(post.Title), speech.Clean(post.Content)
+	for i := range post.Comments {
+		post.Comments[i].Body = speech.Clean(post.Comments[i].Body)
+	}
+	var scrubbed []safety.Scrub
+	if !run.Profile.Safety.KeepPII {
+		scrub := func(text string) string {
+			text, scrubs := safety.ScrubPII(text)
+			scrubbed = append(scrubbed, scrubs...)
+			return text
+		}
+		post.Title, post.Content = scrub(post.Title), scrub(post.Content)
+		for i := range post.Comments {
+			post.Comments[i].Body = scrub(post.Comments[i].Body)
+		}
+	}
+
+	subreddit := run.Input.Subreddit
+	currentDate := time.Now().Format("02.01.2006") // Correct date format
+	text := fmt.Sprintf("%v %v", post.Title, post.Content)
+
+	if settings.Verdict && post.Verdict == nil {
+		log.Printf("No judgements in the comments of post %s, the video has no verdict", post.ID)
+	}
+
+	// The comments are narrated after the post, each under the name of whoever wrote it, or its rank when personal
+	// information is scrubbed, and the verdict last
+	var segments []Segment
+	if len(post.Comments) > 0 || post.Verdict != nil {
+		segments = append(segments, Segment{Heading: post.Title, Text: text})
+		for i, comment := range post.Comments {
+			heading := fmt.Sprintf("u/%s - %d points", comment.Author, comment.Score)
+			if !run.Profile.Safety.KeepPII {
+				heading = fmt.Sprintf("Top comment #%d - %d points", i+1, comment.Score)
+				scrubbed = append(scrubbed, safety.Scrub{Kind: "username", Text: "u/" + comment.Author, Placeholder: fmt.Sprintf("Top comment #%d", i+1)})
+			}
+			segments = append(segments, Segment{Heading: heading, Text: comment.Body})
+			text += " " + comment.Body
+		}
+		if post.Verdict != nil {
+			segment := verdictSegment(post.Verdict)
+			segments = append(segments, segment)
+			text += " " + segment.Text
+		}
+	}
+	if len(scrubbed) > 0 {
+		log.Printf("Scrubbed %d pieces of personal information from post %s", len(scrubbed), post.ID)
+	}
+
+	var verdict, verdictName, verdictPercent string
+	if v := post.Verdict; v != nil {
+		verdict, verdictName, verdictPercent = v.Judgement, reddit.Judgements[v.Judgement], v.Percent()
+	}
+	replacer := strings.NewReplacer("{subreddit}", subreddit, "{date}", currentDate, "{postTitle}", post.Title,
+		"{verdict}", verdict, "{verdictName}", verdictName, "{verdictPercent}", verdictPercent)
+
+	title := fmt.Sprintf("Reddit: %s - %s", subreddit, currentDate)
+	partTitle := fmt.Sprintf("Reddit: %s part {part} of {parts} - %s", subreddit, currentDate)
+	if settings.Title != "" {
+		title = replacer.Replace(settings.Title)
+		partTitle = "" // The parts are numbered after the title
+	}
+	description := fmt.Sprintf("%v? Leave a comment about what you think!", subreddit)
+	if settings.Description != "" {
+		description = replacer.Replace(settings.Description)
+	}
+
+	content := &Content{
+		Kind:        "reddit",
+		ID:          post.ID,
+		Heading:     post.Title,
+		Text:        text,
+		Segments:    segments,
+		Query:       subreddit,
+		Title:       title,
+		Description: description,
+		Source:      post,
+		PartTitle:   partTitle,
+		Scrubbed:    scrubbed,
+	}
+	if err := pickVoice(run, post, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// pickVoice narrates the post in a voice that fits its writer, if the persona of the profile has one. The voices of a
+// pool take turns by post, so a post always gets the same voice. The fallback voices take turns video after video,
+// through a rotation kept in the content history.
+func pickVoice(run *Run, post *reddit.RedditPost, content *Content) error {
+	profile := run.Profile
+	var gender string
+	var age int
+	persona := "not given"
+	if author := post.AuthorTag(); author != nil {
+		gender, age, persona = author.Gender, author.Age, author.String()
+	}
+	voices, reason := profile.Persona.Pool(gender, age)
+	if len(voices) == 0 {
+		return nil
+	}
+
+	var turn int
+	if reason == "fallback" {
+		var err error
+		if turn, err = run.Seen.Turn(profile.Name, "persona.fallback", len(voices)); err != nil {
+			return fmt.Errorf("failed to pick a fallback voice: %v", err)
+		}
+		reason = fmt.Sprintf("fallback[%d]", turn)
+	} else {
+		for _, r := range post.ID {
+			turn = (turn*31 + int(r)) & 0xffff
+		}
+		turn %= len(voices)
+	}
+
+	voice := profile.Voice
+	voice.ID = voices[turn]
+	content.Voice = &voice
+	content.Persona = fmt.Sprintf("%s, persona.%s", persona, reason)
+	log.Printf("Narrating post %s with %s, the writer is %s", post.ID, voice.ID, persona)
+	return nil
+}
+
+// verdictSegment shows the share of every judgement and speaks the winning one
+func verdictSegment(v *reddit.Verdict) Segment {
+	lines := []string{"Verdict"}
+	shares := v.Sorted()
+	if len(shares) > 3 {
+		shares = shares[:3] // The heading has room for four lines
+	}
+	for _, share := range shares {
+		lines = append(lines, fmt.Sprintf("%s %.0f%%", share.Judgement, share.Share*100))
+	}
+	text := fmt.Sprintf("The verdict is in. %s, with %.0f percent of the votes.", reddit.Judgements[v.Judgement], v.Shares[v.Judgement]*100)
+	return Segment{Heading: strings.Join(lines, "\n"), Text: text}
+}
+
+// RecordUsed marks the post as processed for its subreddit, so no channel makes it into a video again. The post was
+// claimed when it was picked, this records when its video was made.
+func (redditSource) RecordUsed(run *Run, content *Content) error {
+	processed, err := openProcessed(run)
+	if err != nil {
+		return err
+	}
+	return processed.Add(run.Input.Subreddit, content.ID)
+}
+
+// openProcessed opens the processed posts of every subreddit, kept next to the content history. It is nil for runs
+// without a content history.
+func openProcessed(run *Run) (*reddit.Processed, error) {
+	if run.Seen == nil {
+		return nil, nil
+	}
+	return reddit.OpenProcessed(filepath.Join(run.Seen.Dir(), reddit.ProcessedFile))
+}
+
+func random(array []string) string {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return array[r.Intn(len(array))]
+}
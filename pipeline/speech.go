@@ -0,0 +1,29 @@
+package pipeline
+
+import (
+	"context"
+
+	"videoCreater/config"
+	"videoCreater/speech"
+)
+
+// narrateText narrates text with the narrator of the pipeline in the voice of the settings. The narrator says the text the way the speech settings
+// of the profile normalize it, and the captions show the text as it is written. Swear words and blocked words of the
+// mask severity are censored, see censorScript.
+func (p *Pipeline) narrateText(ctx context.Context, run *Run, text string, settings config.Voice) (*Narration, error) {
+	script := speech.New(run.Profile.Speech.Expansions).Script(text)
+	bleeped, err := censorScript(run.Profile, script)
+	if err != nil {
+		return nil, err
+	}
+
+	narration, err := p.Narrator.Narrate(ctx, run, script.Text(), settings)
+	if err != nil {
+		return nil, err
+	}
+	narration.WordTimings = script.Align(narration.WordTimings)
+	if err := bleep(narration, bleeped); err != nil {
+		return nil, err
+	}
+	return narration, nil
+}
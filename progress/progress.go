@@ -0,0 +1,128 @@
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stage is a step of the pipeline that makes a video
+type Stage string
+
+const (
+	FetchContent Stage = "fetch-content"
+	TTS          Stage = "tts"
+	Footage      Stage = "footage"
+	Render       Stage = "render"
+	Upload       Stage = "upload"
+)
+
+// Stages lists every stage in the order they run
+var Stages = []Stage{FetchContent, TTS, Footage, Render, Upload}
+
+// Status is the state of a stage
+type Status string
+
+const (
+	Pending Status = "pending"
+	Running Status = "running"
+	Done    Status = "done"
+	Failed  Status = "failed"
+	Skipped Status = "skipped"
+	Resumed Status = "resumed" // Done by an earlier attempt of the run, its outputs were taken from the checkpoint
+)
+
+// StageStatus is the progress of a single stage
+type StageStatus struct {
+	Stage      Stage      `json:"stage"`
+	Status     Status     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Tracker records the progress of the stages of a single run. A nil Tracker is valid and records nothing,
+// so the pipeline can be run without anyone watching.
+type Tracker struct {
+	mu     sync.Mutex
+	stages []*StageStatus
+}
+
+// NewTracker creates a tracker with every stage pending
+func NewTracker() *Tracker {
+	t := &Tracker{}
+	for _, stage := range Stages {
+		t.stages = append(t.stages, &StageStatus{Stage: stage, Status: Pending})
+	}
+	return t
+}
+
+// Start marks a stage as running. It returns the error of ctx if the run was cancelled, so the stage is not started.
+func (t *Tracker) Start(ctx context.Context, stage Stage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.set(stage, Running, nil)
+	return nil
+}
+
+// Finish marks a stage as done, or failed if err is not nil
+func (t *Tracker) Finish(stage Stage, err error) {
+	if err != nil {
+		t.set(stage, Failed, err)
+		return
+	}
+	t.set(stage, Done, nil)
+}
+
+// Skip marks a stage as skipped
+func (t *Tracker) Skip(stage Stage) {
+	t.set(stage, Skipped, nil)
+}
+
+// Resume marks a stage as done by an earlier attempt
+func (t *Tracker) Resume(stage Stage) {
+	t.set(stage, Resumed, nil)
+}
+
+// Snapshot returns a copy of the progress of every stage
+func (t *Tracker) Snapshot() []StageStatus {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot := make([]StageStatus, len(t.stages))
+	for i, s := range t.stages {
+		snapshot[i] = *s
+	}
+	return snapshot
+}
+
+func (t *Tracker) set(stage Stage, status Status, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for _, s := range t.stages {
+		if s.Stage != stage {
+			continue
+		}
+		s.Status = status
+		switch status {
+		case Running:
+			s.StartedAt = &now
+			s.FinishedAt = nil
+			s.Error = ""
+		default:
+			s.FinishedAt = &now
+		}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"videoCreater/jobs"
+)
+
+func runQueue(args []string) error {
+	fs := newFlagSet("queue", "<list|dead|show|requeue> [job id]", "Inspects the job queue of the serve command.\n\n  list            lists the jobs, newest first\n  dead            lists the jobs that ran out of attempts\n  show <id>       prints a job with the progress of every stage\n  requeue <id>    queues a dead or cancelled job again, a running daemon picks it up within seconds")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	state := fs.String("state", "", "only list jobs in this state: queued, running, retrying, succeeded, dead or cancelled")
+	limit := fs.Int("limit", 20, "how many jobs to list, 0 lists every job")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) == 0 {
+		fs.Usage()
+		return fmt.Errorf("queue requires an action")
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	journal, err := jobs.OpenJournal(cfg.QueueDir())
+	if err != nil {
+		return err
+	}
+
+	action := positional[0]
+	switch action {
+	case "list", "dead":
+		if len(positional) > 1 {
+			fs.Usage()
+			return fmt.Errorf("queue %s takes no job id", action)
+		}
+		if action == "dead" {
+			*state = string(jobs.Dead)
+		}
+		saved, err := journal.ReadAll()
+		if err != nil {
+			return err
+		}
+		printJobs(saved, jobs.State(*state), *limit)
+		return nil
+
+	case "show", "requeue":
+		if len(positional) != 2 {
+			fs.Usage()
+			return fmt.Errorf("queue %s requires the job id", action)
+		}
+		id := positional[1]
+
+		if action == "requeue" {
+			job, err := journal.Requeue(id)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Requeued job %s\n", job.ID)
+			return nil
+		}
+
+		saved, err := journal.ReadAll()
+		if err != nil {
+			return err
+		}
+		for _, job := range saved {
+			if job.ID == id {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(job)
+			}
+		}
+		return fmt.Errorf("job %s not found", id)
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown queue action %q", action)
+	}
+}
+
+// printJobs prints one line per job in state, newest first
+func printJobs(saved []jobs.Job, state jobs.State, limit int) {
+	printed := 0
+	for i := len(saved) - 1; i >= 0 && (limit == 0 || printed < limit); i-- {
+		job := saved[i]
+		if state != "" && job.State != state {
+			continue
+		}
+		printed++
+
+		what := job.Request.Type
+		if job.Request.Subreddit != "" {
+			what += " r/" + job.Request.Subreddit
+		}
+		detail := job.Error
+		if job.State == jobs.Retrying && job.NextAttemptAt != nil {
+			detail = "next attempt " + job.NextAttemptAt.Format(time.DateTime) + ": " + detail
+		}
+		if job.FailedStage != "" {
+			detail = string(job.FailedStage) + ": " + detail
+		}
+		fmt.Printf("%-20s %-10s %-20s %d attempts  %s  %s\n", job.ID, job.State, what, job.Attempts, job.CreatedAt.Format(time.DateTime), strings.SplitN(detail, "\n", 2)[0])
+	}
+	if printed == 0 {
+		fmt.Println("No jobs")
+	}
+}
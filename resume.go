@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"videoCreater/jobs"
+	"videoCreater/pipeline"
+)
+
+func runResume(args []string) error {
+	fs := newFlagSet("resume", "[run id]", "Continues a failed run after the last stage it finished, using the outputs kept in its checkpoint.\nWithout a run id the runs that can be resumed are listed.")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	discard := fs.Bool("discard", false, "remove the checkpoint of the run instead of resuming it")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return fmt.Errorf("resume takes at most one run id")
+	}
+
+	cfg, err := initConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		checkpoints, err := pipeline.ListCheckpoints(cfg.CheckpointDir())
+		if err != nil {
+			return err
+		}
+		printCheckpoints(checkpoints)
+		return nil
+	}
+
+	id := positional[0]
+	cp, err := pipeline.LoadCheckpoint(filepath.Join(cfg.CheckpointDir(), id))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("run %s has no checkpoint, it either succeeded or never finished a stage", id)
+	}
+	if err != nil {
+		return err
+	}
+	if *discard {
+		return cp.Remove()
+	}
+
+	req, err := jobs.LoadRequest(cp)
+	if err != nil {
+		return err
+	}
+	profile, err := req.Resolve(cfg)
+	if err != nil {
+		return err
+	}
+	ensureDirExists(profile.OutputDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	_, err = jobs.Execute(ctx, cfg, id, req, nil)
+	return err
+}
+
+// printCheckpoints prints one line per run that can be resumed, with the last stage it finished
+func printCheckpoints(checkpoints []*pipeline.Checkpoint) {
+	if len(checkpoints) == 0 {
+		fmt.Println("No runs to resume")
+		return
+	}
+	for _, cp := range checkpoints {
+		what := "-"
+		if req, err := jobs.LoadRequest(cp); err == nil {
+			what = req.Type
+			if req.Subreddit != "" {
+				what += " r/" + req.Subreddit
+			}
+		}
+		fmt.Printf("%-20s %s %-20s after %s\n", cp.RunID, cp.UpdatedAt.Format(time.DateTime), what, cp.Last())
+	}
+}
@@ -0,0 +1,61 @@
+package safety
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Censor finds swear words in text. A nil Censor finds nothing.
+type Censor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewCensor compiles a word list. A * in a word matches any letters, so f*ck matches feck and fuck, and shit*
+// matches shit, shitty and shitting.
+func NewCensor(words []string) (*Censor, error) {
+	c := &Censor{}
+	for i, word := range words {
+		word = strings.TrimSpace(word)
+		if strings.Trim(word, "*") == "" {
+			return nil, fmt.Errorf("words[%d]: must have a letter", i)
+		}
+		parts := strings.Split(word, "*")
+		for j, part := range parts {
+			parts[j] = regexp.QuoteMeta(part)
+		}
+		pattern, err := regexp.Compile(`(?i)\b` + strings.Join(parts, `\pL*`) + `\b`)
+		if err != nil {
+			return nil, fmt.Errorf("words[%d]: %v", i, err)
+		}
+		c.patterns = append(c.patterns, pattern)
+	}
+	return c, nil
+}
+
+// Mask replaces every letter but the first of the swear words in the text by asterisks, like f***
+func (c *Censor) Mask(text string) string {
+	if c == nil {
+		return text
+	}
+	for _, pattern := range c.patterns {
+		text = pattern.ReplaceAllStringFunc(text, maskWord)
+	}
+	return text
+}
+
+// Replace replaces the swear words in the text by another word
+func (c *Censor) Replace(text, replacement string) string {
+	if c == nil {
+		return text
+	}
+	for _, pattern := range c.patterns {
+		text = pattern.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
+
+// Has returns whether the text has a swear word
+func (c *Censor) Has(text string) bool {
+	return c.Mask(text) != text
+}
@@ -0,0 +1,67 @@
+package safety
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Scrub is a piece of personal information replaced by a placeholder
+type Scrub struct {
+	Kind        string `json:"kind"` // url, email, username, phone or name
+	Text        string `json:"text"`
+	Placeholder string `json:"placeholder"`
+}
+
+// piiPattern finds a kind of personal information. Group 1, if the pattern has it, is kept in front of the placeholder.
+type piiPattern struct {
+	kind        string
+	placeholder string
+	pattern     *regexp.Regexp
+}
+
+// piiPatterns are applied in order, so an email address is not taken for a username and a link is not taken for anything else
+var piiPatterns = []piiPattern{
+	{"url", "a link", regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>()\[\]]*[^\s<>()\[\].,;:!?'"]`)},
+	{"email", "an email address", regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)},
+	{"username", "a user", regexp.MustCompile(`(^|[^\w/])/?u/[A-Za-z0-9_-]{3,20}\b`)},
+	{"phone", "a phone number", regexp.MustCompile(`(^|[^\w+])(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)\s?|\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`)},
+	{"name", "someone", regexp.MustCompile(`\b(?:` + strings.Join(firstNames, "|") + `)(?:\s+[A-Z][a-z]+(?:-[A-Z][a-z]+)?){1,2}\b`)},
+}
+
+// firstNames are common first names. A name is one of them followed by one or two capitalized words, so John Smith is
+// scrubbed but John and Home Depot are not.
+var firstNames = []string{
+	"James", "John", "Robert", "Michael", "William", "David", "Richard", "Joseph", "Thomas", "Charles", "Christopher",
+	"Daniel", "Matthew", "Anthony", "Mark", "Donald", "Steven", "Paul", "Andrew", "Joshua", "Kenneth", "Kevin", "Brian",
+	"George", "Timothy", "Ronald", "Edward", "Jason", "Jeffrey", "Ryan", "Jacob", "Gary", "Nicholas", "Eric", "Jonathan",
+	"Stephen", "Larry", "Justin", "Scott", "Brandon", "Benjamin", "Samuel", "Gregory", "Alexander", "Frank", "Patrick",
+	"Raymond", "Jack", "Dennis", "Jerry", "Tyler", "Aaron", "Jose", "Adam", "Nathan", "Henry", "Peter", "Zachary",
+	"Kyle", "Noah", "Ethan", "Jeremy", "Christian", "Sean", "Austin", "Jordan", "Dylan", "Logan", "Lucas", "Mason",
+	"Liam", "Oliver", "Mary", "Patricia", "Jennifer", "Linda", "Elizabeth", "Barbara", "Susan", "Jessica", "Sarah",
+	"Karen", "Lisa", "Nancy", "Betty", "Margaret", "Sandra", "Ashley", "Kimberly", "Emily", "Donna", "Michelle",
+	"Carol", "Amanda", "Dorothy", "Melissa", "Deborah", "Stephanie", "Rebecca", "Sharon", "Laura", "Cynthia",
+	"Kathleen", "Amy", "Angela", "Shirley", "Anna", "Brenda", "Pamela", "Emma", "Nicole", "Helen", "Samantha",
+	"Katherine", "Christine", "Debra", "Rachel", "Carolyn", "Janet", "Catherine", "Maria", "Heather", "Diane", "Ruth",
+	"Julie", "Olivia", "Joyce", "Virginia", "Victoria", "Kelly", "Lauren", "Christina", "Joan", "Evelyn", "Judith",
+	"Megan", "Andrea", "Cheryl", "Hannah", "Jacqueline", "Martha", "Gloria", "Teresa", "Ann", "Sara", "Madison",
+	"Frances", "Kathryn", "Janice", "Jean", "Abigail", "Alice", "Julia", "Judy", "Sophia", "Grace", "Denise", "Amber",
+	"Danielle", "Marilyn", "Beverly", "Isabella", "Theresa", "Diana", "Natalie", "Brittany", "Charlotte", "Marie",
+	"Kayla", "Alexis", "Lori", "Chloe", "Ava", "Mia", "Jessie", "Taylor", "Morgan", "Alex", "Sam", "Chris", "Jess",
+}
+
+// ScrubPII replaces links, email addresses, Reddit usernames, phone numbers and full names in the text by neutral
+// placeholders, like a link or someone. It returns the scrubbed text and what was replaced.
+func ScrubPII(text string) (string, []Scrub) {
+	var scrubs []Scrub
+	for _, p := range piiPatterns {
+		text = p.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			prefix := ""
+			if p.pattern.NumSubexp() > 0 {
+				prefix = p.pattern.FindStringSubmatch(match)[1]
+			}
+			scrubs = append(scrubs, Scrub{Kind: p.kind, Text: match[len(prefix):], Placeholder: p.placeholder})
+			return prefix + p.placeholder
+		})
+	}
+	return text, scrubs
+}
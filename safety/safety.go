@@ -0,0 +1,97 @@
+package safety
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity is what happens to content with a blocked word
+type Severity string
+
+const (
+	Reject Severity = "reject" // The content is not used
+	Mask   Severity = "mask"   // The word is masked, like d***
+)
+
+// Rule is a blocked word or phrase
+type Rule struct {
+	Word     string   `json:"word"` // Matched as a whole word, ignoring case
+	Severity Severity `json:"severity"`
+}
+
+// Filter finds and masks the blocked words of a blocklist. A nil Filter blocks nothing.
+type Filter struct {
+	reject []*regexp.Regexp
+	mask   []*regexp.Regexp
+	words  map[*regexp.Regexp]string // The rule of every pattern, to report which word was found
+}
+
+// New compiles a blocklist
+func New(rules []Rule) (*Filter, error) {
+	f := &Filter{words: make(map[*regexp.Regexp]string)}
+	for i, rule := range rules {
+		word := strings.TrimSpace(rule.Word)
+		if word == "" {
+			return nil, fmt.Errorf("blocklist[%d]: word must be set", i)
+		}
+		pattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+		if err != nil {
+			return nil, fmt.Errorf("blocklist[%d]: %v", i, err)
+		}
+		f.words[pattern] = word
+
+		switch rule.Severity {
+		case Reject:
+			f.reject = append(f.reject, pattern)
+		case Mask:
+			f.mask = append(f.mask, pattern)
+		default:
+			return nil, fmt.Errorf("blocklist[%d]: severity %q is not reject or mask", i, rule.Severity)
+		}
+	}
+	return f, nil
+}
+
+// Blocked returns the first word with the reject severity found in the text, or an empty string if there is none
+func (f *Filter) Blocked(text string) string {
+	if f == nil {
+		return ""
+	}
+	for _, pattern := range f.reject {
+		if pattern.MatchString(text) {
+			return f.words[pattern]
+		}
+	}
+	return ""
+}
+
+// Mask replaces every letter but the first of the words with the mask severity by asterisks
+func (f *Filter) Mask(text string) string {
+	if f == nil {
+		return text
+	}
+	for _, pattern := range f.mask {
+		text = pattern.ReplaceAllStringFunc(text, maskWord)
+	}
+	return text
+}
+
+// maskWord keeps the first letter of every word of a match, like "f*** y**"
+func maskWord(match string) string {
+	var sb strings.Builder
+	first := true
+	for _, r := range match {
+		switch {
+		case r == ' ':
+			first = true
+			sb.WriteRune(r)
+		case first:
+			first = false
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('*')
+		}
+	}
+	return sb.String()
+}
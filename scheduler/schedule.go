@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a job runs next
+type Schedule interface {
+	// Next returns the first time after the given time the job should run
+	Next(after time.Time) time.Time
+}
+
+// cronSchedule is a standard five field cron expression: minute hour day-of-month month day-of-week
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domStar, dowStar              bool
+}
+
+// ParseCron parses a five field cron expression like "0 9 * * *" or "*/30 8-20 * * 1-5".
+// Fields support *, lists, ranges and steps. Sunday is both 0 and 7.
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, has %d", expr, len(fields))
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron expression %q: minute: %v", expr, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron expression %q: hour: %v", expr, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron expression %q: day of month: %v", expr, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron expression %q: month: %v", expr, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron expression %q: day of week: %v", expr, err)
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return &s, nil
+}
+
+// parseField parses a single cron field into the set of values it matches
+func parseField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is outside %d to %d", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	// Like cron, a restricted day of month and day of week match if either matches
+	if !s.domStar && !s.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Give up after five years, a valid expression always matches before that
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !s.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// every runs a job at a fixed interval
+type every struct {
+	interval time.Duration
+}
+
+// Every returns a schedule that runs a job every interval, starting one interval from now
+func Every(interval time.Duration) Schedule {
+	return &every{interval: interval}
+}
+
+func (e *every) Next(after time.Time) time.Time {
+	return after.Add(e.interval)
+}
+
+// perDay runs a job a number of times a day, spread out over the day with some jitter
+type perDay struct {
+	times  int
+	jitter time.Duration
+	seed   int64
+}
+
+// PerDay returns a schedule that runs a job the given number of times a day. The day is split into equal slots
+// and the job runs in the middle of each slot, moved by up to jitter in either direction. The name is used to
+// make the jitter differ between jobs while staying the same for a slot.
+func PerDay(times int, jitter time.Duration, name string) Schedule {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return &perDay{times: times, jitter: jitter, seed: int64(h.Sum64())}
+}
+
+func (p *perDay) Next(after time.Time) time.Time {
+	slot := 24 * time.Hour / time.Duration(p.times)
+	jitter := p.jitter
+	if jitter > slot/2 {
+		jitter = slot / 2
+	}
+
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	for d := 0; d < 2; d++ {
+		for i := 0; i < p.times; i++ {
+			start := day.AddDate(0, 0, d).Add(time.Duration(i) * slot)
+			at := start.Add(slot / 2)
+			if jitter > 0 {
+				// Seeded by the slot so asking again gives the same time and a slot never runs twice
+				rng := rand.New(rand.NewSource(p.seed ^ start.Unix()))
+				at = at.Add(time.Duration(rng.Int63n(int64(2*jitter))) - jitter)
+			}
+			if at.After(after) {
+				return at
+			}
+		}
+	}
+	return day.AddDate(0, 0, 2)
+}
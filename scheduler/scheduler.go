@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Job is a named task run on a schedule
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on their schedules with at most a fixed number running at the same time
+type Scheduler struct {
+	jobs        []*Job
+	concurrency int
+}
+
+// New creates a scheduler that runs at most concurrency jobs at the same time
+func New(concurrency int) *Scheduler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Scheduler{concurrency: concurrency}
+}
+
+// Add adds a job to the scheduler. Jobs have to be added before Run is called.
+func (s *Scheduler) Add(job *Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run runs the jobs until ctx is cancelled, then waits for the running jobs to finish.
+// A job that is still waiting or running when it is due again is skipped for that time.
+// A failing or panicking job is logged and does not stop the scheduler.
+func (s *Scheduler) Run(ctx context.Context) {
+	if len(s.jobs) == 0 {
+		log.Println("No jobs scheduled")
+		<-ctx.Done()
+		return
+	}
+
+	queue := make(chan *Job, len(s.jobs))
+	var busyMu sync.Mutex
+	busy := make(map[*Job]bool)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				// Jobs still waiting when shutting down are dropped
+				if ctx.Err() == nil {
+					runJob(ctx, job)
+				}
+				busyMu.Lock()
+				delete(busy, job)
+				busyMu.Unlock()
+			}
+		}()
+	}
+
+	now := time.Now()
+	next := make(map[*Job]time.Time)
+	for _, job := range s.jobs {
+		next[job] = job.Schedule.Next(now)
+		log.Printf("Job %s: next run at %s", job.Name, next[job].Format(time.RFC3339))
+	}
+
+	for {
+		// Sleep until the first job is due
+		var due time.Time
+		for _, at := range next {
+			if due.IsZero() || at.Before(due) {
+				due = at
+			}
+		}
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Println("Shutting down, waiting for running jobs to finish")
+			close(queue)
+			wg.Wait()
+			return
+		case now = <-timer.C:
+		}
+
+		for _, job := range s.jobs {
+			if next[job].After(now) {
+				continue
+			}
+			next[job] = job.Schedule.Next(now)
+
+			busyMu.Lock()
+			skip := busy[job]
+			busy[job] = true
+			busyMu.Unlock()
+			if skip {
+				log.Printf("Job %s: skipped, the previous run has not finished. Next run at %s", job.Name, next[job].Format(time.RFC3339))
+				continue
+			}
+
+			queue <- job
+		}
+	}
+}
+
+// runJob runs a single job, turning a panic into a logged error
+func runJob(ctx context.Context, job *Job) {
+	start := time.Now()
+	log.Printf("Job %s: started", job.Name)
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		// Shutting down waits for running jobs, so they are not cancelled with ctx
+		return job.Run(context.WithoutCancel(ctx))
+	}()
+
+	if err != nil {
+		log.Printf("Job %s: failed after %s: %v", job.Name, time.Since(start).Round(time.Second), err)
+		return
+	}
+	log.Printf("Job %s: finished in %s", job.Name, time.Since(start).Round(time.Second))
+}
@@ -0,0 +1,314 @@
+package seen
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"videoCreater/filelock"
+)
+
+// LogFile is the name of the log in the content history directory
+const LogFile string = "content.jsonl"
+
+// lockFileName is the name of the file every process locks while it reads or writes the log, next to the log
+const lockFileName string = "content.lock"
+
+// TurnsFile is the name of the file that holds whose turn it is in every rotation, next to the log
+const TurnsFile string = "turns.json"
+
+// Entry records that a channel used a piece of content
+type Entry struct {
+	Channel string    `json:"channel"` // Profile the content was used by
+	Kind    string    `json:"kind"`    // Where the content came from, like favqs, reddit, pexels or youtube
+	Key     string    `json:"key"`     // Id of the content there, or a hash of a quote
+	RunID   string    `json:"runId"`
+	UsedAt  time.Time `json:"usedAt"`
+}
+
+// Store is the content history: an append only JSON-lines file of the quotes, posts and footage every channel used.
+// Entries older than the window are forgotten. Processes sharing the history take turns through a lock file, so an entry
+// is never lost to another process compacting the file. A nil Store is valid, remembers nothing and reports nothing as used.
+type Store struct {
+	dir    string
+	path   string
+	window time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time // When a channel, kind and key was used last
+}
+
+// Open reads the content history in dir, creating dir if needed. Entries older than window are dropped from the file.
+// A window of 0 keeps every entry forever.
+func Open(dir string, window time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create content history directory: %v", err)
+	}
+	s := &Store{dir: dir, path: filepath.Join(dir, LogFile), window: window}
+
+	release, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	entries, err := s.refresh()
+	if err != nil {
+		return nil, err
+	}
+	var kept []Entry
+	for _, e := range entries {
+		if !s.expired(e.UsedAt) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) < len(entries) {
+		if err := s.rewrite(kept); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Dir returns the directory of the content history, where sources may keep records of their own. It is empty for a nil Store.
+func (s *Store) Dir() string {
+	if s == nil {
+		return ""
+	}
+	return s.dir
+}
+
+// Used returns true if the channel used the content within the window
+func (s *Store) Used(channel, kind, id string) bool {
+	if s == nil || id == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usedAt, ok := s.used[key(channel, kind, id)]
+	return ok && !s.expired(usedAt)
+}
+
+// Record adds entries to the history. The entries other processes recorded since the history was read are read too,
+// so Used reports them afterwards.
+func (s *Store) Record(entries ...Entry) error {
+	if s == nil || len(entries) == 0 {
+		return nil
+	}
+	release, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := s.refresh(); err != nil {
+		return err
+	}
+	return s.append(entries)
+}
+
+// Claim records an entry unless the channel used the content within the window in another run, and returns whether it
+// did. The history is read again under the lock, so of two runs picking the same content at the same time only one
+// gets it.
+func (s *Store) Claim(e Entry) (bool, error) {
+	if s == nil || e.Key == "" {
+		return true, nil
+	}
+	release, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	entries, err := s.refresh()
+	if err != nil {
+		return false, err
+	}
+	for _, used := range entries {
+		if used.Channel == e.Channel && used.Kind == e.Kind && used.Key == e.Key && used.RunID != e.RunID && !s.expired(used.UsedAt) {
+			return false, nil
+		}
+	}
+	return true, s.append([]Entry{e})
+}
+
+// Turn returns whose turn it is in the rotation of a channel through n things, from 0 to n-1, and moves the rotation on
+// to the next one. The turns are kept in TurnsFile, so the rotation goes on across runs and processes. A nil Store
+// keeps no turns and always returns 0.
+func (s *Store) Turn(channel, rotation string, n int) (int, error) {
+	if s == nil || n <= 0 {
+		return 0, nil
+	}
+	release, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	path := filepath.Join(s.dir, TurnsFile)
+	turns := make(map[string]int)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read turns: %v", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &turns); err != nil {
+			return 0, fmt.Errorf("failed to parse turns: %v", err)
+		}
+	}
+
+	k := channel + "/" + rotation
+	turn := turns[k] % n
+	turns[k] = turn + 1
+	if data, err = json.MarshalIndent(turns, "", "  "); err != nil {
+		return 0, fmt.Errorf("failed to marshal turns: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write turns: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("failed to write turns: %v", err)
+	}
+	return turn, nil
+}
+
+// QuoteKey returns the key of a quote: a hash of its words, so the same quote with other punctuation or casing matches
+func QuoteKey(body string) string {
+	words := strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// lock keeps every other goroutine and process from reading or writing the history until release is called
+func (s *Store) lock() (release func(), err error) {
+	s.mu.Lock()
+	unlock, err := filelock.Acquire(filepath.Join(s.dir, lockFileName))
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to lock content history: %v", err)
+	}
+	return func() {
+		unlock()
+		s.mu.Unlock()
+	}, nil
+}
+
+// refresh reads the file again and returns its entries. The store has to be locked.
+func (s *Store) refresh() ([]Entry, error) {
+	entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	s.used = make(map[string]time.Time)
+	for _, e := range entries {
+		if k := key(e.Channel, e.Kind, e.Key); e.UsedAt.After(s.used[k]) {
+			s.used[k] = e.UsedAt
+		}
+	}
+	return entries, nil
+}
+
+// append writes entries to the end of the file. The store has to be locked.
+func (s *Store) append(entries []Entry) error {
+	var data []byte
+	for i := range entries {
+		if entries[i].UsedAt.IsZero() {
+			entries[i].UsedAt = time.Now()
+		}
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal content history entry: %v", err)
+		}
+		data = append(data, append(line, '\n')...)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open content history: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write content history: %v", err)
+	}
+	for _, e := range entries {
+		if k := key(e.Channel, e.Kind, e.Key); e.UsedAt.After(s.used[k]) {
+			s.used[k] = e.UsedAt
+		}
+	}
+	return nil
+}
+
+func (s *Store) expired(usedAt time.Time) bool {
+	return s.window > 0 && time.Since(usedAt) > s.window
+}
+
+// read returns every entry in the file
+func (s *Store) read() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open content history: %v", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse content history line %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read content history: %v", err)
+	}
+	return entries, nil
+}
+
+// rewrite replaces the file with the given entries. The store has to be locked.
+func (s *Store) rewrite(entries []Entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".content-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("failed to compact content history: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to marshal content history entry: %v", err)
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact content history: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact content history: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to compact content history: %v", err)
+	}
+	return nil
+}
+
+func key(channel, kind, id string) string {
+	return channel + "\x00" + kind + "\x00" + id
+}
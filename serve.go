@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"videoCreater/api"
+	"videoCreater/jobs"
+	"videoCreater/scheduler"
+)
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "", "Runs the jobs in the config's daemon section on their schedules until it gets SIGINT or SIGTERM.\nWith an address it also serves the HTTP API to trigger and follow jobs. Running jobs are allowed to finish before it exits.\nJobs are kept in the queue directory and retried following the queue section of the config, see the queue command.")
+	configPath := fs.String("config", "", "path to the config file (default $VIDEOCREATER_CONFIG or config.json)")
+	concurrency := fs.Int("concurrency", 0, "how many jobs can run at the same time, overrides daemon.concurrency")
+	addr := fs.String("addr", "", "address the HTTP API listens on, like 127.0.0.1:8090, overrides daemon.api")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitOnHelp(err)
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("serve takes no arguments")
+	}
+
+	cfg, err := initConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range cfg.ProfileNames() {
+		ensureDirExists(cfg.Profiles[name].OutputDir)
+	}
+
+	if *concurrency == 0 {
+		*concurrency = cfg.Daemon.Concurrency
+	}
+	if *addr == "" {
+		*addr = cfg.Daemon.API
+	}
+	journal, err := jobs.OpenJournal(cfg.QueueDir())
+	if err != nil {
+		return err
+	}
+	manager := jobs.NewManager(cfg, *concurrency, cfg.Daemon.KeepJobs, journal)
+
+	s := scheduler.New(*concurrency)
+	for _, job := range cfg.Daemon.Jobs {
+		schedule, err := job.Schedule()
+		if err != nil {
+			return fmt.Errorf("daemon job %q: %v", job.Name, err)
+		}
+
+		req := jobs.Request{Type: job.Type, Subreddit: job.Subreddit, Theme: job.Theme, Profile: job.Profile, DryRun: job.DryRun}
+		if _, err := req.Resolve(cfg); err != nil {
+			return fmt.Errorf("daemon job %q: %v", job.Name, err)
+		}
+		name := job.Name
+		var last string // Id of the job queued by the previous tick
+		s.Add(&scheduler.Job{
+			Name:     name,
+			Schedule: schedule,
+			Run: func(ctx context.Context) error {
+				// Do not pile up jobs while the previous one is still waiting for a retry
+				if previous, ok := manager.Get(last); ok && !previous.Finished() {
+					log.Printf("Job %q: skipped, %s is still %s", name, previous.ID, previous.State)
+					return nil
+				}
+				queued, err := manager.Submit(req)
+				if err != nil {
+					return err
+				}
+				last = queued.ID
+				return nil
+			},
+		})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := manager.Start(ctx); err != nil {
+		return err
+	}
+
+	var server *http.Server
+	if *addr != "" {
+		server = &http.Server{Addr: *addr, Handler: api.NewServer(manager).Handler()}
+		go func() {
+			log.Printf("Serving the API on http://%s", *addr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("API stopped: %v", err)
+				stop()
+			}
+		}()
+	}
+
+	log.Printf("Serving %d scheduled jobs", len(cfg.Daemon.Jobs))
+	s.Run(ctx)
+
+	if server != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}
+	manager.Shutdown()
+	log.Println("Stopped")
+	return nil
+}
@@ -0,0 +1,234 @@
+package speech
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// currencies are the symbols an amount of money is written with
+const currencies = "$€£"
+
+var currencyNames = map[string][2]string{"$": {"dollars", "cents"}, "€": {"euros", "cents"}, "£": {"pounds", "pence"}}
+
+var (
+	moneyPattern   = regexp.MustCompile(`^([$€£])(\d{1,3}(?:,\d{3})+|\d{1,12})(?:\.(\d{1,2}))?([kKmM])?$`)
+	percentPattern = regexp.MustCompile(`^(\d{1,3}(?:,\d{3})+|\d{1,12})(?:\.(\d+))?%$`)
+	ordinalPattern = regexp.MustCompile(`^(\d{1,9})(?i:st|nd|rd|th)$`)
+	datePattern    = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+	timePattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?i:(am|pm))?$`)
+	yearPattern    = regexp.MustCompile(`^(1[1-9]\d\d|20\d\d)(s)?$`)
+	numberPattern  = regexp.MustCompile(`^(\d{1,3}(?:,\d{3})+|\d{1,12})(?:\.(\d+))?$`)
+)
+
+// units are the words after a number that make it a count or an amount instead of a year, like 1500 dollars
+var units = make(map[string]bool)
+
+func init() {
+	for _, unit := range strings.Fields(`dollar dollars buck bucks euro euros pound pounds cent cents pence usd eur gbp
+		people persons men women kids students employees members users followers subscribers views upvotes downvotes votes
+		comments messages texts words pages items pieces copies steps points calories
+		mile miles km kilometers kilometres meter meters metre metres foot feet ft inch inches lb lbs kg kilos grams
+		second seconds minute minutes hour hours day days week weeks month months year years times`) {
+		units[unit] = true
+	}
+}
+
+var months = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+// sayNumber returns how a number, amount of money, percentage, fraction, date or time is said, or false if the word is
+// none of them. next is the word after it in lower case, empty if punctuation comes between them. A number like 1999
+// is said as a year unless a unit like dollars or people follows it.
+func sayNumber(word, next string) (string, bool) {
+	if r, size := utf8.DecodeLastRuneInString(word); strings.ContainsRune(currencies, r) {
+		word = string(r) + word[:len(word)-size] // Money written like 1500€
+	}
+	if m := moneyPattern.FindStringSubmatch(word); m != nil {
+		names := currencyNames[m[1]]
+		said := cardinal(atoi(m[2]))
+		switch strings.ToLower(m[4]) {
+		case "k":
+			return said + " thousand " + names[0], true
+		case "m":
+			return said + " million " + names[0], true
+		}
+		said += " " + names[0]
+		if cents := atoi(m[3]); cents > 0 {
+			if len(m[3]) == 1 {
+				cents *= 10
+			}
+			said += " and " + cardinal(cents) + " " + names[1]
+		}
+		return said, true
+	}
+	if m := percentPattern.FindStringSubmatch(word); m != nil {
+		return decimal(m[1], m[2]) + " percent", true
+	}
+	if m := ordinalPattern.FindStringSubmatch(word); m != nil {
+		return ordinal(atoi(m[1])), true
+	}
+	if m := datePattern.FindStringSubmatch(word); m != nil {
+		month, day := atoi(m[1]), atoi(m[2])
+		// Without a year or a day past the 12th, like 1/2 or 3/4, a small part of a whole is more likely than a date
+		if m[3] == "" && month >= 1 && month < day && day <= 12 {
+			return fraction(month, day), true
+		}
+		if month > 12 && day <= 12 {
+			month, day = day, month // Written day first, like 25/12
+		}
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return "", false
+		}
+		said := months[month-1] + " " + ordinal(day)
+		switch len(m[3]) {
+		case 2:
+			said += " " + year(2000+atoi(m[3]))
+		case 4:
+			said += " " + year(atoi(m[3]))
+		}
+		return said, true
+	}
+	if m := timePattern.FindStringSubmatch(word); m != nil && (m[2] != "" || m[3] != "") {
+		hour, minutes := atoi(m[1]), atoi(m[2])
+		if hour > 23 || minutes > 59 {
+			return "", false
+		}
+		said := cardinal(hour)
+		switch {
+		case m[2] == "":
+		case minutes == 0 && m[3] == "":
+			said += " hundred"
+		case minutes == 0:
+		case minutes < 10:
+			said += " oh " + cardinal(minutes)
+		default:
+			said += " " + cardinal(minutes)
+		}
+		if m[3] != "" {
+			said += " " + strings.Join(strings.Split(strings.ToUpper(m[3]), ""), " ")
+		}
+		return said, true
+	}
+	if m := yearPattern.FindStringSubmatch(word); m != nil && (m[2] != "" || !units[next]) {
+		said := year(atoi(m[1]))
+		if m[2] != "" {
+			said = plural(said)
+		}
+		return said, true
+	}
+	if m := numberPattern.FindStringSubmatch(word); m != nil {
+		return decimal(m[1], m[2]), true
+	}
+	return "", false
+}
+
+var ones = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten", "eleven", "twelve",
+	"thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+var tens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+var scales = []struct {
+	value int
+	name  string
+}{{1000000000000, "trillion"}, {1000000000, "billion"}, {1000000, "million"}, {1000, "thousand"}}
+
+// cardinal says a whole number, like one hundred twenty three
+func cardinal(n int) string {
+	if n < 0 {
+		return "minus " + cardinal(-n)
+	}
+	var words []string
+	for _, scale := range scales {
+		if n >= scale.value {
+			words = append(words, cardinal(n/scale.value), scale.name)
+			n %= scale.value
+		}
+	}
+	if n >= 100 {
+		words = append(words, ones[n/100], "hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20 && n%10 != 0:
+		words = append(words, tens[n/10]+" "+ones[n%10])
+	case n >= 20:
+		words = append(words, tens[n/10])
+	case n > 0 || len(words) == 0:
+		words = append(words, ones[n])
+	}
+	return strings.Join(words, " ")
+}
+
+var ordinals = map[string]string{"one": "first", "two": "second", "three": "third", "five": "fifth", "eight": "eighth",
+	"nine": "ninth", "twelve": "twelfth"}
+
+// ordinal says the position of a number, like twenty third
+func ordinal(n int) string {
+	words := strings.Fields(cardinal(n))
+	last := words[len(words)-1]
+	switch {
+	case ordinals[last] != "":
+		last = ordinals[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	words[len(words)-1] = last
+	return strings.Join(words, " ")
+}
+
+// year says a year the way years are said, like nineteen ninety nine, two thousand five or twenty twenty three
+func year(n int) string {
+	switch {
+	case n >= 2000 && n < 2010:
+		return cardinal(n)
+	case n%100 == 0:
+		return cardinal(n/100) + " hundred"
+	case n%100 < 10:
+		return cardinal(n/100) + " oh " + cardinal(n%100)
+	default:
+		return cardinal(n/100) + " " + cardinal(n%100)
+	}
+}
+
+// plural says a decade of a year, like nineteen nineties, or more than one part of a whole, like two thirds
+func plural(said string) string {
+	if strings.HasSuffix(said, "y") {
+		return strings.TrimSuffix(said, "y") + "ies"
+	}
+	return said + "s"
+}
+
+// fraction says a part of a whole, like one half, three quarters or two thirds
+func fraction(numerator, denominator int) string {
+	said := ordinal(denominator)
+	switch denominator {
+	case 2:
+		said = "half"
+	case 4:
+		said = "quarter"
+	}
+	if numerator > 1 {
+		said = plural(said)
+	}
+	return cardinal(numerator) + " " + said
+}
+
+// decimal says a number with its fraction, like three point one four
+func decimal(whole, fraction string) string {
+	said := cardinal(atoi(whole))
+	if fraction != "" {
+		var digits []string
+		for _, digit := range fraction {
+			digits = append(digits, ones[digit-'0'])
+		}
+		said += " point " + strings.Join(digits, " ")
+	}
+	return said
+}
+
+// atoi parses a number with thousands separators, 0 if it is empty
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+	return n
+}
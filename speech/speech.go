@@ -0,0 +1,176 @@
+package speech
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"videoCreater/voice"
+)
+
+// Expansions are what the Reddit acronyms are said as
+var Expansions = map[string]string{
+	"AITA":  "am I the a-hole",
+	"AITAH": "am I the a-hole",
+	"WIBTA": "would I be the a-hole",
+	"TIFU":  "today I messed up",
+	"YTA":   "you're the a-hole",
+	"NTA":   "not the a-hole",
+	"ESH":   "everyone sucks here",
+	"NAH":   "no a-holes here",
+	"TL;DR": "too long, didn't read",
+	"TLDR":  "too long, didn't read",
+	"MIL":   "mother in law",
+	"FIL":   "father in law",
+	"SIL":   "sister in law",
+	"BIL":   "brother in law",
+	"DIL":   "daughter in law",
+	"SO":    "significant other",
+	"BF":    "boyfriend",
+	"GF":    "girlfriend",
+	"BFF":   "best friend",
+	"DH":    "husband",
+	"DW":    "wife",
+	"SAHM":  "stay at home mom",
+	"SAHD":  "stay at home dad",
+	"NC":    "no contact",
+	"LC":    "low contact",
+	"IRL":   "in real life",
+	"IMO":   "in my opinion",
+	"IMHO":  "in my humble opinion",
+	"TBH":   "to be honest",
+	"IIRC":  "if I remember correctly",
+	"FWIW":  "for what it's worth",
+	"ETA":   "edited to add",
+	"WTF":   "what the heck",
+	"OMG":   "oh my god",
+}
+
+// Normalizer rewrites text the way a narrator should say it
+type Normalizer struct {
+	expansions map[string]string
+}
+
+// New returns a normalizer that expands the acronyms of Expansions and the given expansions, which take precedence.
+// An expansion to an empty string keeps the word as it is.
+func New(expansions map[string]string) *Normalizer {
+	n := &Normalizer{expansions: make(map[string]string)}
+	for word, said := range Expansions {
+		n.expansions[word] = said
+	}
+	for word, said := range expansions {
+		if said == "" {
+			delete(n.expansions, word)
+			continue
+		}
+		n.expansions[word] = said
+	}
+	return n
+}
+
+// Script returns the text as it is shown and as it is said: acronyms expanded, age and gender tags like (25F) said
+// as twenty five female and numbers, amounts of money, percentages, fractions, dates and times said in words
+func (n *Normalizer) Script(text string) *voice.Script {
+	script := voice.NewScript(text)
+	for i, word := range script.Words {
+		// Punctuation around a word is kept, so the narrator still pauses at it
+		start := strings.IndexFunc(word.Shown, isWordRune)
+		if start < 0 {
+			continue
+		}
+		end := strings.LastIndexFunc(word.Shown, isWordRune) + 1
+		if end < len(word.Shown) && word.Shown[end] == '%' {
+			end++
+		}
+		if r, size := utf8.DecodeLastRuneInString(word.Shown[:start]); strings.ContainsRune(currencies, r) {
+			start -= size // Amounts of money are said with their currency
+		} else if r, size := utf8.DecodeRuneInString(word.Shown[end:]); strings.ContainsRune(currencies, r) {
+			end += size
+		}
+		var next string
+		if end == len(word.Shown) && i+1 < len(script.Words) {
+			next = strings.ToLower(strings.TrimRightFunc(script.Words[i+1].Shown, func(r rune) bool { return !isWordRune(r) }))
+		}
+		if said, ok := n.say(word.Shown[start:end], next); ok {
+			script.Words[i].Said = word.Shown[:start] + said + word.Shown[end:]
+		}
+	}
+	return script
+}
+
+// say returns how a word without the punctuation around it is said, or false if it is said as it is written. next is
+// the word after it, see sayNumber.
+func (n *Normalizer) say(word, next string) (string, bool) {
+	if said, ok := n.expansions[word]; ok {
+		return said, true
+	}
+	// Acronyms of four letters or more are often written in lower case, shorter ones like so are words in lower case
+	if upper := strings.ToUpper(word); len(word) >= 4 && word == strings.ToLower(word) {
+		if said, ok := n.expansions[upper]; ok {
+			return said, true
+		}
+	}
+	if said, ok := sayTag(word); ok {
+		return said, true
+	}
+	return sayNumber(word, next)
+}
+
+// tagPattern matches the age and gender tags people describe themselves with, like 25F or M30
+var tagPattern = regexp.MustCompile(`^(?i)(?:(\d{1,2})(m|f|nb)|(m|f|nb)(\d{1,2}))$`)
+
+var genders = map[string]string{"m": "male", "f": "female", "nb": "non binary"}
+
+func sayTag(word string) (string, bool) {
+	m := tagPattern.FindStringSubmatch(word)
+	if m == nil {
+		return "", false
+	}
+	age, gender := m[1]+m[4], strings.ToLower(m[2]+m[3])
+	return cardinal(atoi(age)) + " " + genders[gender], true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+var markdown = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`>!(.+?)!<`), "$1"},                                // Spoilers
+	{regexp.MustCompile(`(?m)^\s*(?:[-*_]\s*){3,}$`), ""},                  // Horizontal rules
+	{regexp.MustCompile(`(?m)^\s*#{1,6}\s+`), ""},                          // Headings
+	{regexp.MustCompile(`(?m)^\s*(?:>\s?)+`), ""},                          // Quotes
+	{regexp.MustCompile(`(?m)^\s*[-*+]\s+`), ""},                           // Lists
+	{regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`), "$1"},                  // Links
+	{regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`), "$1"},                   // Bold
+	{regexp.MustCompile(`__(\S(?:.*?\S)?)__`), "$1"},                       // Bold
+	{regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*`), "$1$2"}, // Italic
+	{regexp.MustCompile(`(^|\W)_([^_\s](?:[^_]*[^_\s])?)_`), "$1$2"},       // Italic
+	{regexp.MustCompile(`~~(.+?)~~`), "$1"},                                // Strikethrough
+	{regexp.MustCompile("`+([^`]*)`+"), "$1"},                              // Code
+	{regexp.MustCompile(`\^\(([^)]*)\)`), "$1"},                            // Superscript
+	{regexp.MustCompile(`\\([\\*_~#>\[\]()!^` + "`" + `-])`), "$1"},        // Escaped characters
+}
+
+// Clean strips the markdown and HTML entities of Reddit text, like **bold**, [links](https://...) and &amp;
+func Clean(text string) string {
+	// Reddit escapes entities twice in places, like &amp;#x200B; for a zero width space
+	text = html.UnescapeString(html.UnescapeString(text))
+	for _, m := range markdown {
+		text = m.pattern.ReplaceAllString(text, m.replace)
+	}
+	text = strings.Map(func(r rune) rune {
+		switch r {
+		case '\u200b', '\ufeff':
+			return -1
+		case '\u00a0':
+			return ' '
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(text)
+}
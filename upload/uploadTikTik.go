@@ -2,6 +2,7 @@ package upload
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -10,7 +11,7 @@ import (
 )
 
 // UploadVideoTikTok uploads a video file to TikTok along with a title, description, and makes it public.
-func UploadVideoTikTok(filePath, title, description string) error {
+func UploadVideoTikTok(ctx context.Context, filePath, title, description string) error {
 	// Open the video file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -53,7 +54,7 @@ func UploadVideoTikTok(filePath, title, description string) error {
 	}
 
 	// Create the POST request
-	req, err := http.NewRequest("POST", "https://api.tiktok.com/upload/video", &b)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.tiktok.com/upload/video", &b)
 	if err != nil {
 		return err
 	}
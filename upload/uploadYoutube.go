@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -19,27 +18,37 @@ import (
 )
 
 // getClient retrieves the authenticated HTTP client using OAuth2
-func getClient(ctx context.Context, config *oauth2.Config) *http.Client {
+func getClient(ctx context.Context, config *oauth2.Config) (*http.Client, error) {
 	tokenFile := "token.json"
 	token, err := tokenFromFile(tokenFile)
 	if err != nil {
-		token = getTokenFromWeb(ctx, config)
-		saveToken(tokenFile, token)
+		token, err = getTokenFromWeb(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveToken(tokenFile, token); err != nil {
+			return nil, err
+		}
 	} else if token.Expiry.Before(time.Now()) {
 		// Check if the token is expired and attempt to refresh it
 		if token.RefreshToken == "" {
 			fmt.Println("Refresh token not available. Please re-authenticate.")
-			token = getTokenFromWeb(ctx, config)
+			token, err = getTokenFromWeb(ctx, config)
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			newToken, err := config.TokenSource(ctx, token).Token() // This will automatically use the refresh token
 			if err != nil {
-				log.Fatalf("Failed to refresh access token: %v", err)
+				return nil, fmt.Errorf("failed to refresh access token: %v", err)
+			}
+			if err := saveToken(tokenFile, newToken); err != nil {
+				return nil, err
 			}
-			saveToken(tokenFile, newToken)
 			token = newToken
 		}
 	}
-	return config.Client(ctx, token)
+	return config.Client(ctx, token), nil
 }
 
 // tokenFromFile retrieves the token from the file
@@ -55,14 +64,15 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 }
 
 // getTokenFromWeb retrieves the token from the web by prompting the user
-func getTokenFromWeb(ctx context.Context, config *oauth2.Config) *oauth2.Token {
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
 	state := "state-token"
 	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline)
 	fmt.Printf("Go to the following link in your browser then type the authorization code: \n%v\n", authURL)
 
-	codeChan := make(chan string)
+	codeChan := make(chan string, 1)
 	// Start a local server to handle the redirect
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		query := r.URL.Query()
 		if query.Get("state") != state {
 			http.Error(w, "State does not match", http.StatusBadRequest)
@@ -74,29 +84,39 @@ func getTokenFromWeb(ctx context.Context, config *oauth2.Config) *oauth2.Token {
 			return
 		}
 		fmt.Fprintf(w, "Authorization complete, you can close this window.")
-		codeChan <- code
+		select {
+		case codeChan <- code:
+		default:
+		}
 	})
 
-	go http.ListenAndServe(":8080", nil)
+	server := &http.Server{Addr: ":8080", Handler: mux}
+	go server.ListenAndServe()
+	defer server.Close()
 
-	authCode := <-codeChan
+	var authCode string
+	select {
+	case authCode = <-codeChan:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("stopped waiting for authorization: %v", ctx.Err())
+	}
 
 	token, err := config.Exchange(ctx, authCode)
 	if err != nil {
-		log.Fatalf("Unable to retrieve token from web: %v", err)
+		return nil, fmt.Errorf("unable to retrieve token from web: %v", err)
 	}
-	return token
+	return token, nil
 }
 
 // saveToken saves the token to a file for later use
-func saveToken(path string, token *oauth2.Token) {
+func saveToken(path string, token *oauth2.Token) error {
 	fmt.Printf("Saving credential file to: %s\n", path)
 	f, err := os.Create(path)
 	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+		return fmt.Errorf("unable to cache oauth token: %v", err)
 	}
 	defer f.Close()
-	json.NewEncoder(f).Encode(token)
+	return json.NewEncoder(f).Encode(token)
 }
 
 // sanitizeDescription ensures the description is valid for YouTube
@@ -121,25 +141,27 @@ func sanitizeDescription(baseDescription string, tags []string) string {
 	return description
 }
 
-func UploadVideoYoutube(videoPath, description, title, categoryID string, tags []string) error {
-	ctx := context.Background()
-
+// UploadVideoYoutube uploads a video to YouTube and returns the id of the uploaded video
+func UploadVideoYoutube(ctx context.Context, videoPath, description, title, categoryID string, tags []string, privacy string, madeForKids bool) (string, error) {
 	b, err := os.ReadFile("client_secret.json")
 	if err != nil {
-		return fmt.Errorf("unable to read client secret file: %v", err)
+		return "", fmt.Errorf("unable to read client secret file: %v", err)
 	}
 
 	config, err := google.ConfigFromJSON(b, youtube.YoutubeUploadScope)
 	if err != nil {
-		return fmt.Errorf("unable to parse client secret file to config: %v", err)
+		return "", fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
 	config.RedirectURL = "http://localhost:8080"
 
-	client := getClient(ctx, config)
+	client, err := getClient(ctx, config)
+	if err != nil {
+		return "", err
+	}
 
 	service, err := youtube.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return fmt.Errorf("unable to create YouTube service: %v", err)
+		return "", fmt.Errorf("unable to create YouTube service: %v", err)
 	}
 
 	postTags := make([]string, 0, global.TagLimit) // Initialize an empty slice with capacity for tagLimit elements
@@ -162,9 +184,9 @@ func UploadVideoYoutube(videoPath, description, title, categoryID string, tags [
 			CategoryId:  categoryID, // People & Blogs category
 		},
 		Status: &youtube.VideoStatus{
-			PrivacyStatus:           "public",
-			MadeForKids:             global.MadeForKids,
-			SelfDeclaredMadeForKids: global.MadeForKids,
+			PrivacyStatus:           privacy,
+			MadeForKids:             madeForKids,
+			SelfDeclaredMadeForKids: madeForKids,
 		},
 	}
 
@@ -172,16 +194,16 @@ func UploadVideoYoutube(videoPath, description, title, categoryID string, tags [
 
 	file, err := os.Open(videoPath)
 	if err != nil {
-		return fmt.Errorf("error opening video file: %v", err)
+		return "", fmt.Errorf("error opening video file: %v", err)
 	}
 	defer file.Close()
 
-	response, err := call.Media(file).Do()
+	response, err := call.Context(ctx).Media(file).Do()
 	if err != nil {
-		return fmt.Errorf("error uploading video: %v", err)
+		return "", fmt.Errorf("error uploading video: %v", err)
 	}
 
 	fmt.Printf("Upload successful! Video ID: %v\n", response.Id)
 
-	return nil
+	return response.Id, nil
 }
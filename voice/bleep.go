@@ -0,0 +1,41 @@
+package voice
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Bleep plays a tone over the words in the audio file at path, instead of the words
+func Bleep(path string, words []WordInfo) error {
+	if len(words) == 0 {
+		return nil
+	}
+	var ranges []string
+	for _, word := range words {
+		ranges = append(ranges, fmt.Sprintf("between(t,%f,%f)", word.StartTime, word.EndTime))
+	}
+	bleeped := strings.Join(ranges, "+")
+
+	// The voice is muted and the tone is heard while a word is spoken
+	filter := fmt.Sprintf("[0:a]volume=0:enable='%s'[voice];[1:a]volume=0.3,volume=0:enable='not(%s)'[tone];[voice][tone]amix=inputs=2:duration=first:normalize=0[a]",
+		bleeped, bleeped)
+
+	tmp := filepath.Join(filepath.Dir(path), "bleeped-"+filepath.Base(path))
+	cmd := exec.Command("ffmpeg", "-xerror",
+		"-i", path,
+		"-f", "lavfi", "-i", "sine=frequency=1000",
+		"-filter_complex", filter,
+		"-map", "[a]",
+		"-y", tmp)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to bleep %s: %v, output: %s", path, err, string(output))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to bleep %s: %v", path, err)
+	}
+	return nil
+}
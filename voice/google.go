@@ -0,0 +1,122 @@
+package voice
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+
+	"videoCreater/config"
+
+	"google.golang.org/api/option"
+	texttospeech "google.golang.org/api/texttospeech/v1beta1"
+)
+
+// endMark is the SSML mark after the last word, telling when it ends
+const endMark = "end"
+
+// maxSSMLBytes is the most SSML Google narrates in a single request
+const maxSSMLBytes = 5000
+
+// googleTTS narrates with Google Cloud Text-to-Speech. It authenticates with the API key in GOOGLE_TTS_API_KEY if set,
+// and with the application default credentials otherwise, like the service account GOOGLE_APPLICATION_CREDENTIALS points to.
+// It uses the v1beta1 REST client of google.golang.org/api, since only the v1beta1 API returns the times of SSML marks,
+// which the captions are timed by. The gRPC client of cloud.google.com/go/texttospeech only has the v1 API.
+type googleTTS struct{}
+
+// fits counts the marks around every word, which make the SSML several times longer than the text
+func (googleTTS) fits(text string) bool {
+	return len(markedSSML(strings.Fields(text))) <= maxSSMLBytes
+}
+
+func (googleTTS) synthesize(ctx context.Context, text string, settings config.Voice, path string) ([]WordInfo, error) {
+	var opts []option.ClientOption
+	if key := os.Getenv("GOOGLE_TTS_API_KEY"); key != "" {
+		opts = append(opts, option.WithAPIKey(key))
+	}
+	service, err := texttospeech.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Text-to-Speech client: %v", err)
+	}
+
+	words := strings.Fields(text)
+
+	req := &texttospeech.SynthesizeSpeechRequest{
+		Input: &texttospeech.SynthesisInput{Ssml: markedSSML(words)},
+		Voice: &texttospeech.VoiceSelectionParams{
+			LanguageCode: languageCode(settings.ID),
+			Name:         settings.ID,
+		},
+		AudioConfig: &texttospeech.AudioConfig{
+			AudioEncoding: "MP3",
+			SpeakingRate:  max(1+settings.Speed, 0.25), // Speed -1 to 1 is a rate of 0.25 to 2
+			Pitch:         (settings.Pitch - 1) * 20,   // Pitch 0.5 to 1.5 is -10 to 10 semitones
+		},
+		EnableTimePointing: []string{"SSML_MARK"},
+	}
+	resp, err := service.Text.Synthesize(req).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Google Text-to-Speech failed: %v", err)
+	}
+
+	audio, err := base64.StdEncoding.DecodeString(resp.AudioContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Google Text-to-Speech audio: %v", err)
+	}
+	if err := os.WriteFile(path, audio, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file %s: %v", path, err)
+	}
+
+	return markTimings(words, resp.Timepoints), nil
+}
+
+// markedSSML returns the SSML of the words with every word marked, so Google tells when it is said
+func markedSSML(words []string) string {
+	var ssml strings.Builder
+	ssml.WriteString("<speak>")
+	for i, word := range words {
+		fmt.Fprintf(&ssml, `<mark name="%d"/>%s `, i, html.EscapeString(word))
+	}
+	fmt.Fprintf(&ssml, `<mark name="%s"/></speak>`, endMark)
+	return ssml.String()
+}
+
+// markTimings returns the timings of the words from the times their marks were reached. A word whose mark is missing
+// starts when the word before it does.
+func markTimings(words []string, timepoints []*texttospeech.Timepoint) []WordInfo {
+	times := make([]float64, len(words)+1) // When every word starts, and the last one ends
+	found := make([]bool, len(times))
+	for _, tp := range timepoints {
+		i := len(words)
+		if tp.MarkName != endMark {
+			var err error
+			if i, err = strconv.Atoi(tp.MarkName); err != nil || i < 0 || i >= len(words) {
+				continue
+			}
+		}
+		times[i], found[i] = tp.TimeSeconds, true
+	}
+	for i := 1; i < len(times); i++ {
+		if !found[i] {
+			times[i] = times[i-1]
+		}
+	}
+
+	wordInfos := make([]WordInfo, len(words))
+	for i, word := range words {
+		wordInfos[i] = WordInfo{StartTime: times[i], EndTime: times[i+1], Word: word}
+	}
+	return wordInfos
+}
+
+// languageCode returns the language of a Google voice, like en-US of en-US-Neural2-F
+func languageCode(name string) string {
+	parts := strings.SplitN(name, "-", 3)
+	if len(parts) < 2 {
+		return name
+	}
+	return parts[0] + "-" + parts[1]
+}
@@ -0,0 +1,66 @@
+package voice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Lexicon is how the narrator should say the words it gets wrong, like names, slang and brands. It maps a word to a
+// phonetic respelling or a replacement, like Nguyen to win. Words match ignoring case.
+type Lexicon map[string]string
+
+// LoadLexicon reads a lexicon from a JSON file of words and how they are said. It returns nil if path is empty.
+func LoadLexicon(path string) (Lexicon, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lexicon: %v", err)
+	}
+	var words map[string]string
+	if err := json.Unmarshal(data, &words); err != nil {
+		return nil, fmt.Errorf("failed to parse lexicon %s: %v", path, err)
+	}
+	lexicon := make(Lexicon, len(words))
+	for word, said := range words {
+		if strings.TrimSpace(word) == "" || strings.TrimSpace(said) == "" {
+			return nil, fmt.Errorf("failed to parse lexicon %s: %q must have a word and how it is said", path, word)
+		}
+		lexicon[strings.ToLower(word)] = said
+	}
+	return lexicon, nil
+}
+
+// Script returns the text with the words of the lexicon said the way the lexicon says them. The punctuation around a
+// word and a possessive 's are kept.
+func (l Lexicon) Script(text string) *Script {
+	script := NewScript(text)
+	if len(l) == 0 {
+		return script
+	}
+	for i, word := range script.Words {
+		start := strings.IndexFunc(word.Shown, isWordRune)
+		if start < 0 {
+			continue
+		}
+		end := strings.LastIndexFunc(word.Shown, isWordRune) + 1
+		core, suffix := word.Shown[start:end], ""
+		said, ok := l[strings.ToLower(core)]
+		if !ok {
+			for _, possessive := range []string{"'s", "’s"} {
+				if base := strings.TrimSuffix(core, possessive); base != core {
+					said, ok = l[strings.ToLower(base)]
+					suffix = possessive
+					break
+				}
+			}
+		}
+		if ok {
+			script.Words[i].Said = word.Shown[:start] + said + suffix + word.Shown[end:]
+		}
+	}
+	return script
+}
@@ -0,0 +1,121 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"videoCreater/config"
+	"videoCreater/global"
+)
+
+// local is implemented by the providers running on this machine. They take as long as the text takes to say, so a
+// chunk is only stopped when the run is, not after chunkTimeout.
+type local interface {
+	local()
+}
+
+func (piper) local()  {}
+func (espeak) local() {}
+
+// piper narrates offline with the piper engine, in the voice of the .onnx model the ID of the voice points to
+type piper struct{}
+
+func (piper) fits(text string) bool {
+	return len(text) <= global.MaxVoiceCharacters
+}
+
+func (piper) synthesize(ctx context.Context, text string, settings config.Voice, path string) ([]WordInfo, error) {
+	// A length scale below 1 is faster, speed -1 to 1 is a scale of 2 to 0.67. Piper has no pitch.
+	lengthScale := 1 / max(1+settings.Speed/2, 0.5)
+	return synthesizeLocally(ctx, text, settings, path, func(wav string) *exec.Cmd {
+		return exec.CommandContext(ctx, "piper",
+			"--model", settings.ID,
+			"--length_scale", strconv.FormatFloat(lengthScale, 'f', 2, 64),
+			"--output_file", wav)
+	}, nil)
+}
+
+// espeak narrates offline with espeak-ng, in the voice the ID of the voice names, like en-us
+type espeak struct{}
+
+func (espeak) fits(text string) bool {
+	return len(text) <= global.MaxVoiceCharacters
+}
+
+func (espeak) synthesize(ctx context.Context, text string, settings config.Voice, path string) ([]WordInfo, error) {
+	wordsPerMinute := 175 + int(settings.Speed*85) // Speed -1 to 1 is 90 to 260 words a minute
+	pitch := min(max(int((settings.Pitch-0.5)*99), 0), 99)
+	return synthesizeLocally(ctx, text, settings, path, func(wav string) *exec.Cmd {
+		return exec.CommandContext(ctx, "espeak-ng",
+			"-v", settings.ID,
+			"-s", strconv.Itoa(wordsPerMinute),
+			"-p", strconv.Itoa(pitch),
+			"-w", wav,
+			"--stdin")
+	}, func() *exec.Cmd {
+		return exec.CommandContext(ctx, "espeak-ng", "-q", "-x", "-v", settings.ID, "--stdin")
+	})
+}
+
+// synthesizeLocally runs the command of an engine, which reads the text from stdin and writes a WAV file, and converts
+// the WAV file to the MP3 file at path. Neither engine tells when the words are said, so the timings are estimated from
+// the pauses in the audio and the length of the words: the phonemes the engine says them with if it has a phonemes
+// command, which writes the phonemes of every word of stdin, or their syllables otherwise.
+func synthesizeLocally(ctx context.Context, text string, settings config.Voice, path string, command func(wav string) *exec.Cmd, phonemes func() *exec.Cmd) ([]WordInfo, error) {
+	wav := strings.TrimSuffix(path, ".mp3") + ".wav"
+	defer os.Remove(wav)
+
+	cmd := command(wav)
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s stopped: %v", cmd.Args[0], ctx.Err())
+		}
+		return nil, fmt.Errorf("%s failed: %v, output: %s", cmd.Args[0], err, string(output))
+	}
+
+	samples, rate, err := readWAV(wav)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(text)
+	var weights []float64
+	if phonemes != nil {
+		weights = phonemeWeights(phonemes(), text, len(words))
+	}
+	wordInfos := estimateTimings(words, weights, speechSpans(samples, rate))
+
+	args := []string{"-xerror", "-i", wav}
+	if settings.Bitrate != "" {
+		args = append(args, "-b:a", settings.Bitrate)
+	}
+	args = append(args, "-y", path)
+	if output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to convert %s to MP3: %v, output: %s", wav, err, string(output))
+	}
+	return wordInfos, nil
+}
+
+// phonemeWeights returns how many phonemes the engine says every word of the text with, or nil if the command fails or
+// its words do not match the words of the text, like when it reads a number as several words
+func phonemeWeights(cmd *exec.Cmd, text string, words int) []float64 {
+	cmd.Stdin = strings.NewReader(text)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	said := strings.Fields(string(output))
+	if len(said) != words {
+		return nil
+	}
+	weights := make([]float64, words)
+	for i, word := range said {
+		// Stress and syllable marks are not said
+		weights[i] = float64(max(len(strings.NewReplacer("'", "", ",", "", "_", "", "-", "").Replace(word)), 1))
+	}
+	return weights
+}
@@ -0,0 +1,123 @@
+package voice
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Script is text that is said differently than it is shown, like AITA said as am I the a-hole. It remembers which
+// shown word every said word belongs to, so the word timings of the narration can be mapped back to the shown words.
+type Script struct {
+	Words []ScriptWord
+}
+
+// ScriptWord is a word as it is shown and as it is said
+type ScriptWord struct {
+	Shown string
+	Said  string // Any number of words, empty to not say the word
+}
+
+// NewScript returns a script that says every word of the text as it is shown
+func NewScript(text string) *Script {
+	s := &Script{}
+	for _, word := range strings.Fields(text) {
+		s.Words = append(s.Words, ScriptWord{Shown: word, Said: word})
+	}
+	return s
+}
+
+// Text returns what is said
+func (s *Script) Text() string {
+	var said []string
+	for _, word := range s.Words {
+		if word.Said != "" {
+			said = append(said, word.Said)
+		}
+	}
+	return strings.Join(said, " ")
+}
+
+// Shown returns what is shown
+func (s *Script) Shown() string {
+	var shown []string
+	for _, word := range s.Words {
+		shown = append(shown, word.Shown)
+	}
+	return strings.Join(shown, " ")
+}
+
+// maxSkipped is how many said words the narrator may leave out before a word of the timings is no longer looked for
+const maxSkipped = 5
+
+// Align maps the timings of the said words back to the shown words: the said words of a shown word become a single
+// word spanning all of them. The timings may be split in parts, like the chunks of a narration, which are aligned one
+// after the other. Words of the timings that are not in the script are kept as they are.
+func (s *Script) Align(timings [][]WordInfo) [][]WordInfo {
+	type token struct {
+		word  string
+		owner int
+	}
+	var tokens []token
+	for i, word := range s.Words {
+		for _, said := range strings.Fields(word.Said) {
+			if norm := normalizeWord(said); norm != "" {
+				tokens = append(tokens, token{norm, i})
+			}
+		}
+	}
+
+	aligned := make([][]WordInfo, len(timings))
+	next, lastOwner := 0, -1
+	rest := "" // What is left of a said word the narrator split in several words
+	for part, infos := range timings {
+		lastOwner, rest = -1, "" // A word does not span two parts
+		for _, info := range infos {
+			norm := normalizeWord(info.Word)
+			owner := -1
+			if rest != "" && norm != "" && strings.HasPrefix(rest, norm) {
+				owner, rest = lastOwner, rest[len(norm):]
+			} else {
+				rest = ""
+				for j := next; j < len(tokens) && j < next+maxSkipped && norm != ""; j++ {
+					if tokens[j].word == norm {
+						owner, next = tokens[j].owner, j+1
+						break
+					}
+				}
+				// The narrator may split a word, like a-hole in a and hole
+				if owner < 0 && next < len(tokens) && norm != "" && strings.HasPrefix(tokens[next].word, norm) {
+					owner, rest = tokens[next].owner, tokens[next].word[len(norm):]
+					next++
+				}
+			}
+
+			words := aligned[part]
+			switch {
+			case owner < 0:
+				aligned[part] = append(words, info)
+				lastOwner = -1
+			case owner == lastOwner && len(words) > 0:
+				words[len(words)-1].EndTime = info.EndTime
+			default:
+				aligned[part] = append(words, WordInfo{StartTime: info.StartTime, EndTime: info.EndTime, Word: s.Words[owner].Shown})
+				lastOwner = owner
+			}
+		}
+	}
+	return aligned
+}
+
+// normalizeWord keeps the letters and numbers of a word in lower case, so words compare the same whatever punctuation
+// the narrator returns them with
+func normalizeWord(word string) string {
+	return strings.Map(func(r rune) rune {
+		if isWordRune(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, word)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
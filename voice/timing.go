@@ -0,0 +1,199 @@
+package voice
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const (
+	frameLength      = 0.01 // Seconds of audio the loudness is measured over
+	silenceThreshold = 0.05 // Frames quieter than this share of the loudest frame are silent
+	minPause         = 0.15 // Seconds of silence between two phrases
+)
+
+// span is a stretch of the audio in seconds
+type span struct {
+	start, end float64
+}
+
+// readWAV returns the samples of a 16 bit PCM WAV file, mixed down to mono, and their sample rate
+func readWAV(path string) ([]float64, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("%s is not a WAV file", path)
+	}
+
+	var channels, bits, rate int
+	for offset := 12; offset+8 <= len(data); {
+		id, size := string(data[offset:offset+4]), int(binary.LittleEndian.Uint32(data[offset+4:offset+8]))
+		body := data[offset+8:]
+		if size > len(body) {
+			size = len(body) // Engines writing to a pipe leave the size unknown
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, 0, fmt.Errorf("%s has a broken fmt chunk", path)
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			rate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+		case "data":
+			if bits != 16 || channels == 0 {
+				return nil, 0, fmt.Errorf("%s is not 16 bit PCM", path)
+			}
+			samples := make([]float64, len(body)/2/channels)
+			for i := range samples {
+				var sum float64
+				for c := 0; c < channels; c++ {
+					at := (i*channels + c) * 2
+					sum += float64(int16(binary.LittleEndian.Uint16(body[at:at+2]))) / 32768
+				}
+				samples[i] = sum / float64(channels)
+			}
+			return samples, rate, nil
+		}
+		offset += 8 + size + size%2 // Chunks are padded to an even size
+	}
+	return nil, 0, fmt.Errorf("%s has no audio data", path)
+}
+
+// speechSpans returns the stretches of the audio where something is said, split at pauses of at least minPause
+func speechSpans(samples []float64, rate int) []span {
+	frame := int(float64(rate) * frameLength)
+	if frame == 0 {
+		return nil
+	}
+	var loudness []float64
+	var loudest float64
+	for start := 0; start < len(samples); start += frame {
+		end := min(start+frame, len(samples))
+		var sum float64
+		for _, s := range samples[start:end] {
+			sum += s * s
+		}
+		rms := math.Sqrt(sum / float64(end-start))
+		loudness = append(loudness, rms)
+		loudest = max(loudest, rms)
+	}
+
+	var spans []span
+	quiet := 0 // Silent frames since the last stretch
+	for i, rms := range loudness {
+		if rms <= loudest*silenceThreshold {
+			quiet++
+			continue
+		}
+		at := float64(i) * frameLength
+		// A silence too short for a pause does not end the last stretch
+		if len(spans) == 0 || float64(quiet)*frameLength >= minPause {
+			spans = append(spans, span{start: at})
+		}
+		spans[len(spans)-1].end = at + frameLength
+		quiet = 0
+	}
+	return spans
+}
+
+// estimateTimings spreads the words said over the stretches where something is said, giving every word a share of the
+// time by its weight, or by its syllables if weights is nil. When the words have as many phrases, split at punctuation,
+// as the audio has stretches, every phrase is spread over its own stretch.
+func estimateTimings(words []string, weights []float64, spans []span) []WordInfo {
+	if len(words) == 0 {
+		return nil
+	}
+	if len(spans) == 0 {
+		spans = []span{{0, 0}}
+	}
+	if len(weights) != len(words) {
+		weights = make([]float64, len(words))
+		for i, word := range words {
+			weights[i] = float64(countSyllables(word))
+		}
+	}
+
+	var phrases []int // Index of the first word after every phrase
+	for i, word := range words {
+		if strings.ContainsAny(word[len(word)-1:], ".,!?;:") || i == len(words)-1 {
+			phrases = append(phrases, i+1)
+		}
+	}
+
+	if len(phrases) != len(spans) {
+		return spread(words, weights, spans)
+	}
+	var wordInfos []WordInfo
+	start := 0
+	for i, end := range phrases {
+		wordInfos = append(wordInfos, spread(words[start:end], weights[start:end], spans[i:i+1])...)
+		start = end
+	}
+	return wordInfos
+}
+
+// spread gives every word a share of the time of the spans by its weight, skipping the silences between the spans
+func spread(words []string, weights []float64, spans []span) []WordInfo {
+	var total float64
+	for _, s := range spans {
+		total += s.end - s.start
+	}
+	var sum float64
+	for _, weight := range weights {
+		sum += weight
+	}
+
+	// at returns the time in the audio after the given seconds of speech. A word that starts where a span ends starts
+	// at the next span instead.
+	at := func(speech float64, starts bool) float64 {
+		for _, s := range spans {
+			if speech < s.end-s.start || (!starts && speech == s.end-s.start) {
+				return s.start + speech
+			}
+			speech -= s.end - s.start
+		}
+		return spans[len(spans)-1].end
+	}
+
+	wordInfos := make([]WordInfo, len(words))
+	var said float64
+	for i, word := range words {
+		start := at(said, true)
+		said += total * weights[i] / sum
+		wordInfos[i] = WordInfo{StartTime: start, EndTime: at(said, false), Word: word}
+	}
+	return wordInfos
+}
+
+// countSyllables estimates the syllables of a word by its groups of vowels, and of a number by its digits
+func countSyllables(word string) int {
+	count, inVowels := 0, false
+	letters := strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) }))
+	for _, r := range letters {
+		switch {
+		case unicode.IsNumber(r):
+			count++
+			inVowels = false
+		case strings.ContainsRune("aeiouy", r):
+			if !inVowels {
+				count++
+			}
+			inVowels = true
+		default:
+			inVowels = false
+		}
+	}
+	// A silent e at the end, like in make
+	if strings.HasSuffix(letters, "e") && !strings.HasSuffix(letters, "le") && count > 1 {
+		count--
+	}
+	return max(count, 1)
+}
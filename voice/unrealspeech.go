@@ -0,0 +1,106 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"videoCreater/config"
+	"videoCreater/global"
+)
+
+// UnrealSpeechResponse is the struct to unmarshal the response from UnrealSpeech's API
+type UnrealSpeechResponse struct {
+	OutputUri     string `json:"OutputUri"`
+	TimestampsUri string `json:"TimestampsUri"`
+}
+
+// unrealSpeech narrates with the UnrealSpeech API
+type unrealSpeech struct{}
+
+func (unrealSpeech) fits(text string) bool {
+	return len(text) <= global.MaxVoiceCharacters
+}
+
+func (unrealSpeech) synthesize(ctx context.Context, text string, settings config.Voice, path string) ([]WordInfo, error) {
+	apiKey := os.Getenv("UNREAL_SPEECH_API_KEY")
+	if apiKey == "" {
+		log.Println("API key is not set")
+		return nil, fmt.Errorf("UNREAL_SPEECH_API_KEY environment variable is not set")
+	}
+
+	reqBody := map[string]interface{}{
+		"Text":          text,
+		"VoiceId":       settings.ID,
+		"Bitrate":       settings.Bitrate,
+		"Speed":         strconv.FormatFloat(settings.Speed, 'f', -1, 64),
+		"Pitch":         strconv.FormatFloat(settings.Pitch, 'f', -1, 64),
+		"TimestampType": "word",
+	}
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	url := "https://api.v6.unrealspeech.com/speech"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to UnrealSpeech API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("API returned non-OK status: %s, body: %s", resp.Status, string(body))
+		return nil, fmt.Errorf("UnrealSpeech API returned non-OK status: %s, body: %s", resp.Status, string(body))
+	}
+
+	var apiResponse UnrealSpeechResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	// Download the MP3 file from OutputUri
+	err = downloadFile(ctx, apiResponse.OutputUri, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download MP3 file: %v", err)
+	}
+
+	// Download the JSON file from TimestampsUri
+	req, err = http.NewRequestWithContext(ctx, "GET", apiResponse.TimestampsUri, nil)
+	if err == nil {
+		resp, err = http.DefaultClient.Do(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download JSON from URL %s: %v", apiResponse.TimestampsUri, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download JSON from URL %s: status code %d", apiResponse.TimestampsUri, resp.StatusCode)
+	}
+
+	var wordInfos []WordInfo
+	if err := json.NewDecoder(resp.Body).Decode(&wordInfos); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+
+	return wordInfos, nil
+}
@@ -1,9 +1,7 @@
 package voice
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -13,15 +11,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"videoCreater/global"
+	"unicode"
+	"videoCreater/config"
 )
 
-// UnrealSpeechResponse is the struct to unmarshal the response from UnrealSpeech's API
-type UnrealSpeechResponse struct {
-	OutputUri     string `json:"OutputUri"`
-	TimestampsUri string `json:"TimestampsUri"`
-}
-
 // WordInfo contains the timing information for a word
 type WordInfo struct {
 	StartTime float64 `json:"start"` // The start time of the word in the audio
@@ -55,8 +48,12 @@ func findNextAvailableFilename(dir string, extension string) (string, error) {
 }
 
 // downloadFile downloads a file from the given URL and writes it to the given path
-func downloadFile(url string, path string) error {
-	resp, err := http.Get(url)
+func downloadFile(ctx context.Context, url string, path string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download file from URL %s: %v", url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download file from URL %s: %v", url, err)
 	}
@@ -80,15 +77,69 @@ func downloadFile(url string, path string) error {
 	return nil
 }
 
-// ConvertTextToSpeech sends text to UnrealSpeech API and returns the path to the saved MP3 file and the timing information of words
-func ConvertTextToSpeech(text string) ([]string, [][]WordInfo, error) {
-	chunks := assembleChunks(text)
+// chunkTimeout is how long a remote provider has to narrate a single chunk
+const chunkTimeout = 30 * time.Second
+
+// provider turns a chunk of text into speech
+type provider interface {
+	// synthesize says the text in the voice of the settings, saving the MP3 file at path, and returns when every word is said
+	synthesize(ctx context.Context, text string, settings config.Voice, path string) ([]WordInfo, error)
+	// fits returns whether the provider can say the text in a single request
+	fits(text string) bool
+}
+
+// providers are the text to speech services by the name voice.provider has in the config
+var providers = map[string]provider{
+	config.UnrealSpeech: unrealSpeech{},
+	config.GoogleTTS:    googleTTS{},
+	config.Piper:        piper{},
+	config.Espeak:       espeak{},
+}
+
+// ConvertTextToSpeech sends text to the text to speech provider of the voice and returns the paths to the MP3 files saved in dir,
+// the timing information of words and the voice that narrated the text. When the provider fails, like when its quota is used up,
+// and the voice has a fallback, the text is narrated by the fallback instead. Nothing is narrated once ctx is done.
+func ConvertTextToSpeech(ctx context.Context, text string, settings config.Voice, dir string) ([]string, [][]WordInfo, config.Voice, error) {
+	paths, wordInfos, err := convertTextToSpeech(ctx, text, settings, dir)
+	if err == nil || settings.Fallback == nil {
+		return paths, wordInfos, settings, err
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// The provider did not fail, the run was cancelled or ran out of time
+		return nil, nil, settings, err
+	}
+
+	fallback := *settings.Fallback
+	if fallback.Lexicon == "" {
+		fallback.Lexicon = settings.Lexicon
+	}
+	log.Printf("%s failed, narrating with %s %s instead: %v", settings.ProviderName(), fallback.ProviderName(), fallback.ID, err)
+	paths, wordInfos, fallbackErr := convertTextToSpeech(ctx, text, fallback, dir)
+	if fallbackErr != nil {
+		return nil, nil, fallback, fmt.Errorf("%v, and the fallback failed too: %v", err, fallbackErr)
+	}
+	return paths, wordInfos, fallback, nil
+}
+
+// convertTextToSpeech narrates the text chunk by chunk with the provider of the voice. The files of the chunks are
+// removed if a chunk fails.
+func convertTextToSpeech(ctx context.Context, text string, settings config.Voice, dir string) ([]string, [][]WordInfo, error) {
+	lexicon, err := LoadLexicon(settings.Lexicon)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The chunks are measured as they are said
+	provider := providers[settings.ProviderName()]
+	chunks := assembleChunks(text, func(chunk string) bool { return provider.fits(lexicon.Script(chunk).Text()) })
 	var paths []string
 	var allWordInfos [][]WordInfo
 
 	for _, chunk := range chunks {
-		path, wordInfos, err := processTextChunk(chunk)
+		path, wordInfos, err := processTextChunk(ctx, chunk, settings, lexicon, dir)
 		if err != nil {
+			for _, path := range paths {
+				os.Remove(path)
+			}
 			return nil, nil, err
 		}
 		paths = append(paths, path)
@@ -98,29 +149,26 @@ func ConvertTextToSpeech(text string) ([]string, [][]WordInfo, error) {
 	return paths, allWordInfos, nil
 }
 
-// assembleChunks divides text into chunks that do not exceed the maximum size defined in global.MaxVoiceCharacters, respecting sentence boundaries.
-func assembleChunks(text string) []string {
+// assembleChunks divides text into chunks for which fits returns true, respecting sentence boundaries. A sentence that
+// does not fit on its own is divided between words.
+func assembleChunks(text string, fits func(chunk string) bool) []string {
 	var chunks []string
 	var currentChunk strings.Builder
-	sentences := strings.FieldsFunc(text, func(r rune) bool {
-		return r == '.' || r == '?' || r == '!'
-	})
-
-	currentLength := 0
-	for _, sentence := range sentences {
-		sentence = strings.TrimSpace(sentence) + " " // Add the punctuation back with a space for natural reading
-		sentenceLength := len(sentence)
-
-		if currentLength+sentenceLength > global.MaxVoiceCharacters {
-			if currentChunk.Len() > 0 {
-				chunks = append(chunks, currentChunk.String())
-				currentChunk.Reset()
-				currentLength = 0
-			}
+	for _, sentence := range splitSentences(text) {
+		sentence += " " // A space before the next sentence
+
+		if currentChunk.Len() > 0 && !fits(currentChunk.String()+sentence) {
+			chunks = append(chunks, currentChunk.String())
+			currentChunk.Reset()
+		}
+		if currentChunk.Len() == 0 && !fits(sentence) {
+			pieces := splitWords(sentence, fits)
+			chunks = append(chunks, pieces[:len(pieces)-1]...)
+			currentChunk.WriteString(pieces[len(pieces)-1])
+			continue
 		}
 
 		currentChunk.WriteString(sentence)
-		currentLength += sentenceLength
 	}
 
 	// Ensure the last chunk is added
@@ -131,61 +179,67 @@ func assembleChunks(text string) []string {
 	return chunks
 }
 
-// processTextChunk handles the interaction with the UnrealSpeech API for a single text chunk
-func processTextChunk(text string) (string, []WordInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	apiKey := os.Getenv("UNREAL_SPEECH_API_KEY")
-	if apiKey == "" {
-		log.Println("API key is not set")
-		return "", nil, fmt.Errorf("UNREAL_SPEECH_API_KEY environment variable is not set")
-	}
-
-	reqBody := map[string]interface{}{
-		"Text":          text,
-		"VoiceId":       global.VoiceID,
-		"Bitrate":       global.Bitrate,
-		"Speed":         global.VoiceSpeed,
-		"Pitch":         global.VoicePitch,
-		"TimestampType": "word",
-	}
-	reqBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to marshal request: %v", err)
+// splitSentences divides text after the punctuation ending a sentence, keeping the punctuation so the narrator pauses
+// and the pauses can be matched to the sentences
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		if start < 0 {
+			start = i
+		}
+		// A sentence ends at a space after its punctuation, not at the point of a number like 3.5 or inside quotes
+		if next := nextRune(text[i+1:]); strings.ContainsRune(".?!", r) && (next == 0 || unicode.IsSpace(next)) {
+			if sentence := strings.TrimSpace(text[start : i+1]); strings.IndexFunc(sentence, isWordRune) >= 0 {
+				sentences = append(sentences, sentence)
+			}
+			start = -1
+		}
 	}
-
-	url := "https://api.v6.unrealspeech.com/speech"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBytes))
-	if err != nil {
-		return "", nil, err
+	if start >= 0 {
+		if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
 	}
+	return sentences
+}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to send request to UnrealSpeech API: %v", err)
+// nextRune returns the first rune of text, or 0 if it is empty
+func nextRune(text string) rune {
+	for _, r := range text {
+		return r
 	}
-	defer resp.Body.Close()
+	return 0
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("API returned non-OK status: %s, body: %s", resp.Status, string(body))
-		return "", nil, fmt.Errorf("UnrealSpeech API returned non-OK status: %s, body: %s", resp.Status, string(body))
+// splitWords divides a sentence into pieces for which fits returns true, between words. A single word is a piece even
+// if it does not fit.
+func splitWords(sentence string, fits func(chunk string) bool) []string {
+	var pieces []string
+	piece := ""
+	for _, word := range strings.Fields(sentence) {
+		if piece != "" && !fits(piece+word+" ") {
+			pieces = append(pieces, piece)
+			piece = ""
+		}
+		piece += word + " "
 	}
+	return append(pieces, piece)
+}
 
-	var apiResponse UnrealSpeechResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return "", nil, fmt.Errorf("failed to decode response: %v", err)
+// processTextChunk narrates a single text chunk with the provider of the voice, saving the MP3 file in dir.
+// The words of the lexicon are said the way it says them, and their timings are mapped back to the words of the chunk.
+// A remote provider has chunkTimeout to narrate the chunk, a local one as long as ctx allows.
+func processTextChunk(ctx context.Context, text string, settings config.Voice, lexicon Lexicon, dir string) (string, []WordInfo, error) {
+	provider := providers[settings.ProviderName()]
+	if _, ok := provider.(local); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, chunkTimeout)
+		defer cancel()
 	}
 
-	dir := "text-to-speeched"
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.Mkdir(dir, 0755); err != nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
 			return "", nil, fmt.Errorf("failed to create directory: %v", err)
 		}
 	}
@@ -195,27 +249,11 @@ func processTextChunk(text string) (string, []WordInfo, error) {
 		return "", nil, err
 	}
 
-	// Download the MP3 file from OutputUri
-	err = downloadFile(apiResponse.OutputUri, path)
+	script := lexicon.Script(text)
+	wordInfos, err := provider.synthesize(ctx, script.Text(), settings, path)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to download MP3 file: %v", err)
-	}
-
-	// Download the JSON file from TimestampsUri
-	resp, err = http.Get(apiResponse.TimestampsUri)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to download JSON from URL %s: %v", apiResponse.TimestampsUri, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", nil, fmt.Errorf("failed to download JSON from URL %s: status code %d", apiResponse.TimestampsUri, resp.StatusCode)
-	}
-
-	var wordInfos []WordInfo
-	if err := json.NewDecoder(resp.Body).Decode(&wordInfos); err != nil {
-		return "", nil, fmt.Errorf("failed to decode JSON response: %v", err)
+		os.Remove(path)
+		return "", nil, err
 	}
-
-	return path, wordInfos, nil
+	return path, script.Align([][]WordInfo{wordInfos})[0], nil
 }
@@ -0,0 +1,53 @@
+package workspace
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Directories inside a workspace
+const (
+	RawVideos      string = "raw-videos"       // Downloaded footage
+	TextToSpeeched string = "text-to-speeched" // Narration audio
+	Rendered       string = "rendered"         // Finished videos and the logos burned into them
+)
+
+// Workspace is the directory holding every intermediate file of a single run, so runs can not clobber each other's files
+type Workspace struct {
+	ID   string // Id of the run
+	Dir  string
+	Keep bool // Keep the directory on Close, for debugging
+}
+
+// New creates the workspace of run id under root. An existing workspace of the same run is reused.
+func New(root, id string, keep bool) (*Workspace, error) {
+	if id == "" {
+		return nil, fmt.Errorf("workspace needs a run id")
+	}
+	w := &Workspace{ID: id, Dir: filepath.Join(root, id), Keep: keep}
+	for _, dir := range []string{RawVideos, TextToSpeeched, Rendered} {
+		if err := os.MkdirAll(filepath.Join(w.Dir, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create workspace: %v", err)
+		}
+	}
+	return w, nil
+}
+
+// Path returns the path of a directory or file inside the workspace
+func (w *Workspace) Path(elem ...string) string {
+	return filepath.Join(append([]string{w.Dir}, elem...)...)
+}
+
+// Close removes the workspace and everything in it, unless it is kept
+func (w *Workspace) Close() error {
+	if w.Keep {
+		log.Printf("Kept workspace %s", w.Dir)
+		return nil
+	}
+	if err := os.RemoveAll(w.Dir); err != nil {
+		return fmt.Errorf("failed to remove workspace %s: %v", w.Dir, err)
+	}
+	return nil
+}